@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// ----- How many path sets reach the best turn count (--stats) -----
+// Two solvers can print different moves and both be right. On maps the
+// exact solver handles, every set of disjoint paths is tried and the ones
+// needing the fewest turns are counted. On larger maps the searches are
+// run again on copies of the farm with shuffled tunnels; the distinct
+// sets needing the fewest turns found that way give a lower bound. Only
+// paths that carry ants count, so a set with an unused extra path is not
+// told apart from the same set without it.
+
+// Shuffled searches run on maps too large for the exact count
+const alternativeSamples = 16
+
+type optimalSets struct {
+	turns int
+	count int
+	exact bool // count is exact, not a lower bound
+}
+
+func countOptimalSets(f *Farm) optimalSets {
+	if len(f.Rooms) <= defaultExactRooms {
+		if paths, err := allSimplePaths(f, maxExactPaths); err == nil && len(paths) > 0 {
+			return exactOptimalSets(f, paths)
+		}
+	}
+	return sampledOptimalSets(f, alternativeSamples)
+}
+
+func exactOptimalSets(f *Farm, paths [][]string) optimalSets {
+	best := optimalSets{turns: -1, exact: true}
+	keys := make(map[string]bool)
+	forEachDisjointSet(paths, func(set [][]string) {
+		turns := predictTurns(set, f.Ants, maxAntsPerPath)
+		if best.turns >= 0 && turns > best.turns {
+			return
+		}
+		if turns < best.turns || best.turns < 0 {
+			best.turns = turns
+			keys = make(map[string]bool)
+		}
+		keys[pathSetKey(f.Ants, set)] = true
+	})
+	best.count = len(keys)
+	return best
+}
+
+func sampledOptimalSets(f *Farm, samples int) optimalSets {
+	best := optimalSets{turns: -1}
+	keys := make(map[string]bool)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < samples; i++ {
+		farm := f
+		if i > 0 {
+			farm = shuffledTunnels(f, rng)
+		}
+		for _, strategy := range []Strategy{autoPaths, flowPaths} {
+			paths, _ := disjointPaths(strategy(farm))
+			if len(paths) == 0 {
+				continue
+			}
+			turns := predictTurns(paths, f.Ants, maxAntsPerPath)
+			if best.turns >= 0 && turns > best.turns {
+				continue
+			}
+			if turns < best.turns || best.turns < 0 {
+				best.turns = turns
+				keys = make(map[string]bool)
+			}
+			keys[pathSetKey(f.Ants, paths)] = true
+		}
+	}
+	best.count = len(keys)
+	return best
+}
+
+// shuffledTunnels is a copy of the farm with every room's tunnels in a
+// random order, which changes the ties the searches break
+func shuffledTunnels(f *Farm, rng *rand.Rand) *Farm {
+	c := f.Clone()
+	for _, name := range c.Names {
+		links := c.Rooms[name].Links
+		rng.Shuffle(len(links), func(i, j int) { links[i], links[j] = links[j], links[i] })
+	}
+	return c
+}
+
+// pathSetKey names the paths of the set that get ants, in any order
+func pathSetKey(ants int, paths [][]string) string {
+	var used []string
+	for i, group := range distributeAnts(ants, paths, 0) {
+		if len(group) > 0 {
+			used = append(used, strings.Join(paths[i], "\x00"))
+		}
+	}
+	sort.Strings(used)
+	return strings.Join(used, "\n")
+}
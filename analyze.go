@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ----- analyze: structural report on a map -----
+// lem-in analyze map.txt
+func runAnalyze(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: go run . analyze input.txt")
+		return
+	}
+	farm, err := parseInput(args[0], ParseOptions{})
+	if err != nil {
+		reportError(err)
+		return
+	}
+
+	stats := farm.Stats()
+	fmt.Println("=== Size ===")
+	fmt.Printf("Rooms: %d, tunnels: %d\n", stats.Rooms, stats.Tunnels)
+	var degrees []string
+	for d, n := range stats.Degrees {
+		if n > 0 {
+			degrees = append(degrees, fmt.Sprintf("%d: %d", d, n))
+		}
+	}
+	fmt.Printf("Rooms by number of tunnels: %s\n", strings.Join(degrees, ", "))
+
+	rooms, tunnels := criticalParts(farm)
+	fmt.Println("\n=== Connectivity ===")
+	if len(rooms) == 0 && len(tunnels) == 0 {
+		fmt.Println("No single room or tunnel disconnects start from end")
+	}
+	if len(rooms) > 0 {
+		fmt.Printf("Articulation rooms: %s\n", strings.Join(rooms, ", "))
+	}
+	if len(tunnels) > 0 {
+		fmt.Printf("Bridge tunnels:     %s\n", strings.Join(tunnels, ", "))
+	}
+
+	printTradeOffs(farm)
+}
+
+// ----- Turns against total moves for each number of paths -----
+type tradeOff struct {
+	paths, turns, moves int
+}
+
+// tradeOffs uses the k shortest of the solver's disjoint paths, for every
+// k, with the ants spread to minimize turns. More paths never cost turns
+// but can add moves: ants then take longer routes to arrive sooner.
+func tradeOffs(f *Farm) (rows []tradeOff, used int) {
+	paths := append([][]string(nil), autoPaths(f)...)
+	sort.SliceStable(paths, func(i, j int) bool { return len(paths[i]) < len(paths[j]) })
+	for k := 1; k <= len(paths); k++ {
+		row := tradeOff{paths: k, turns: predictTurns(paths[:k], f.Ants, 0)}
+		used = 0
+		for i, group := range distributeAnts(f.Ants, paths[:k], 0) {
+			row.moves += len(group) * (len(paths[i]) - 1)
+			if len(group) > 0 {
+				used++
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, used
+}
+
+// dominated reports whether another row is at least as good on both
+// turns and moves, and better on one of them
+func dominated(row tradeOff, rows []tradeOff) bool {
+	for _, other := range rows {
+		if other.turns <= row.turns && other.moves <= row.moves &&
+			(other.turns < row.turns || other.moves < row.moves) {
+			return true
+		}
+	}
+	return false
+}
+
+func printTradeOffs(f *Farm) {
+	rows, used := tradeOffs(f)
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Println("\n=== Trade-offs ===")
+	fmt.Printf("%6s %8s %8s\n", "paths", "turns", "moves")
+	for _, row := range rows {
+		mark := ""
+		if !dominated(row, rows) {
+			mark = " pareto"
+		}
+		if row.paths == used {
+			mark += " (solver)"
+		}
+		fmt.Printf("%6d %8d %8d%s\n", row.paths, row.turns, row.moves, mark)
+	}
+}
+
+// ----- Articulation rooms and bridge tunnels between start and end -----
+// A room or tunnel whose removal disconnects start from end lies on every
+// start-end route, so on the shortest one in particular: only the parts
+// of that route need to be tried.
+func criticalParts(f *Farm) (rooms []string, tunnels []string) {
+	route := shortestRoute(f, "", [2]string{})
+	if route == nil {
+		return nil, nil
+	}
+	for i, name := range route {
+		if i > 0 && i < len(route)-1 && shortestRoute(f, name, [2]string{}) == nil {
+			rooms = append(rooms, name)
+		}
+		if i > 0 {
+			tunnel := [2]string{route[i-1], name}
+			if shortestRoute(f, "", tunnel) == nil {
+				tunnels = append(tunnels, quoteName(tunnel[0])+"-"+quoteName(tunnel[1]))
+			}
+		}
+	}
+	return rooms, tunnels
+}
+
+// shortestRoute runs a BFS from start to end without the given room and
+// tunnel (either may be empty) and returns the route, or nil
+func shortestRoute(f *Farm, blockedRoom string, blockedTunnel [2]string) []string {
+	previous := map[string]string{f.Start: ""}
+	queue := []string{f.Start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == f.End {
+			var route []string
+			for room := current; room != ""; room = previous[room] {
+				route = append([]string{room}, route...)
+			}
+			return route
+		}
+		for _, next := range f.Rooms[current].Links {
+			if _, seen := previous[next]; seen || next == blockedRoom {
+				continue
+			}
+			if (current == blockedTunnel[0] && next == blockedTunnel[1]) ||
+				(current == blockedTunnel[1] && next == blockedTunnel[0]) {
+				continue
+			}
+			previous[next] = current
+			queue = append(queue, next)
+		}
+	}
+	return nil
+}
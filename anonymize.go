@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ----- anonymize: rename every room before sharing a map -----
+// lem-in anonymize [--mapping file] map.txt > shared.txt
+// lem-in anonymize --reverse file solution.txt
+// Rooms become r0, r1, ... in input order and zones z0, z1, ...; every
+// line keeps its place and only comments are dropped. The mapping file,
+// one "new<TAB>original" line per name, turns the moves (or the whole
+// --strict output) for the shared map back into the original names.
+func runAnonymize(args []string) {
+	fs := flag.NewFlagSet("anonymize", flag.ExitOnError)
+	mapping := fs.String("mapping", "", "also write the new and original names to this file")
+	reverse := fs.String("reverse", "", "put back the original names in a solution, using this mapping file")
+	args, _ = parseFlags(fs, args)
+	if len(args) != 1 {
+		fmt.Println("Usage: go run . anonymize [--mapping file] input.txt")
+		fmt.Println("       go run . anonymize --reverse file solution.txt")
+		return
+	}
+	if *reverse != "" {
+		if err := deanonymize(os.Stdout, *reverse, args[0]); err != nil {
+			reportError(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if isBinaryFarm(args[0]) {
+		reportErrorf("anonymize needs a text map, convert it with --to text first")
+		return
+	}
+	farm, err := parseInput(args[0], ParseOptions{})
+	if err != nil {
+		reportError(err)
+		return
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		reportError(err)
+		return
+	}
+	lines, names, err := anonymizeMap(strings.Split(string(data), "\n"), farm)
+	if err != nil {
+		reportError(err)
+		return
+	}
+	if *mapping != "" {
+		err := writeFile(*mapping, farm, func(w io.Writer, _ *Farm) error {
+			for _, pair := range names {
+				if _, err := fmt.Fprintf(w, "%s\t%s\n", pair[0], pair[1]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			reportError(err)
+			return
+		}
+	}
+	fmt.Print(strings.Join(lines, "\n") + "\n")
+}
+
+// anonymizeMap rewrites the lines of a map that parsed as f, keeping
+// their order so that the tunnels, and with them the paths found, stay the
+// same. It also returns the new and original name of every room and zone.
+func anonymizeMap(lines []string, f *Farm) ([]string, [][2]string, error) {
+	renamed := make(map[string]string, len(f.Names))
+	var names [][2]string
+	for i, name := range f.Names {
+		renamed[name] = "r" + strconv.Itoa(i)
+		names = append(names, [2]string{renamed[name], name})
+	}
+	for i, zone := range f.Zones {
+		renamed[zone.Name] = "z" + strconv.Itoa(i)
+		names = append(names, [2]string{renamed[zone.Name], zone.Name})
+	}
+
+	var out []string
+	counted := false
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || (strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "##")):
+			continue
+		case !counted:
+			counted = true
+		case strings.HasPrefix(line, "##zone"):
+			fields := strings.Fields(line)
+			for i := 1; i < len(fields); i++ {
+				fields[i] = renamed[fields[i]]
+			}
+			line = strings.Join(fields, " ")
+		case strings.HasPrefix(line, "##"):
+		case strings.Contains(line, " "):
+			fields := strings.Fields(line)
+			name, err := unquoteName(fields[0])
+			if err != nil {
+				return nil, nil, err
+			}
+			line = renamed[name] + " " + fields[1] + " " + fields[2]
+		default:
+			a, b, err := splitTunnel(line, f, ParseOptions{})
+			if err != nil {
+				return nil, nil, err
+			}
+			line = renamed[a] + "-" + renamed[b]
+		}
+		out = append(out, line)
+	}
+	return out, names, nil
+}
+
+// deanonymize copies a solution to w with the original room names back
+func deanonymize(w io.Writer, mappingFile, solutionFile string) error {
+	data, err := os.ReadFile(mappingFile)
+	if err != nil {
+		return err
+	}
+	original := make(map[string]string)
+	for i, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		newName, name, ok := strings.Cut(line, "\t")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected new and original name separated by a tab", mappingFile, i+1)
+		}
+		original[newName] = name
+	}
+	lookup := func(name string) string {
+		if o, ok := original[name]; ok {
+			return o
+		}
+		return name
+	}
+
+	file, err := os.Open(solutionFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	out := bufio.NewWriter(w)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) > 1 && fields[0] == "##zone":
+			for i := 1; i < len(fields); i++ {
+				fields[i] = lookup(fields[i])
+			}
+			line = strings.Join(fields, " ")
+		case len(fields) == 0 || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "L"):
+			// Moves: L<ant>-<room> ...
+			for i, move := range fields {
+				if ant, room, ok := strings.Cut(move, "-"); ok {
+					fields[i] = ant + "-" + lookup(room)
+				}
+			}
+			line = strings.Join(fields, " ")
+		case len(fields) == 3:
+			// A room of the map echoed by --strict
+			line = quoteName(lookup(fields[0])) + " " + fields[1] + " " + fields[2]
+		case strings.Count(line, "-") == 1:
+			a, b, _ := strings.Cut(line, "-")
+			line = quoteName(lookup(a)) + "-" + quoteName(lookup(b))
+		}
+		if _, err := fmt.Fprintln(out, line); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return out.Flush()
+}
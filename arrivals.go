@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+)
+
+// ----- Arrivals at the end room (--arrivals) -----
+// Throughput graphs only need to know when each ant gets to the end, so
+// arrivals have their own observer instead of a parse of the move log.
+
+// arrivals returns a turn observer calling arrived(ant, turn) for every
+// ant entering the end room; turns are numbered from 1.
+func arrivals(end string, arrived func(ant, turn int)) func(Turn) {
+	turn := 0
+	return func(moves Turn) {
+		turn++
+		for _, m := range moves {
+			if m.Room == end {
+				arrived(m.Ant, turn)
+			}
+		}
+	}
+}
+
+// An arrivalLog writes one "ant turn" line per arrival to a file
+type arrivalLog struct {
+	file *os.File
+	out  *bufio.Writer
+}
+
+func newArrivalLog(filename string) (*arrivalLog, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &arrivalLog{file: file, out: bufio.NewWriter(file)}, nil
+}
+
+// arrived ignores write errors, which the bufio.Writer keeps for close
+func (l *arrivalLog) arrived(ant, turn int) {
+	fmt.Fprintf(l.out, "%d %d\n", ant, turn)
+}
+
+func (l *arrivalLog) close() error {
+	err := l.out.Flush()
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+package main
+
+// ----- Searching from the end (--algo backward) -----
+// Disjoint paths use distinct start neighbours and distinct end
+// neighbours, so there are never more of them than the smaller of the two
+// counts. When the end has fewer neighbours than the start, a search that
+// grows one path per start neighbour wastes its time on paths that all
+// meet at the same few rooms before the end. The backward search grows
+// one path per end neighbour instead, on the farm turned around.
+
+// pathLimit is the largest number of disjoint paths the degrees allow
+func pathLimit(f *Farm) int {
+	distinct := func(links []string) int {
+		seen := make(map[string]bool, len(links))
+		for _, l := range links {
+			seen[l] = true
+		}
+		return len(seen)
+	}
+	from, to := distinct(f.Rooms[f.Start].Links), distinct(f.Rooms[f.End].Links)
+	if to < from {
+		return to
+	}
+	return from
+}
+
+// endIsBottleneck tells whether the end has fewer neighbours than start
+func endIsBottleneck(f *Farm) bool {
+	return f.Degree(f.End) < f.Degree(f.Start)
+}
+
+// backwardPaths runs findNonOverlappingPaths from the end room and turns
+// the paths it finds around
+func backwardPaths(f *Farm) [][]string {
+	reversed := f.Clone()
+	reversed.Start, reversed.End = f.End, f.Start
+	paths := findNonOverlappingPaths(reversed)
+	for _, p := range paths {
+		for i, j := 0, len(p)-1; i < j; i, j = i+1, j-1 {
+			p[i], p[j] = p[j], p[i]
+		}
+	}
+	return paths
+}
+
+// preferBackward returns the backward paths instead of the forward ones
+// when the end is the bottleneck and they need fewer turns; on a tie the
+// forward paths stay. It also returns the backward paths it tried, and
+// whether they were kept.
+func preferBackward(f *Farm, forward [][]string) ([][]string, [][]string, bool) {
+	if !endIsBottleneck(f) {
+		return forward, nil, false
+	}
+	back, _ := disjointPaths(backwardPaths(f))
+	if len(back) > 0 && (len(forward) == 0 || predictTurns(back, f.Ants, maxAntsPerPath) < predictTurns(forward, f.Ants, maxAntsPerPath)) {
+		return back, back, true
+	}
+	return forward, back, false
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// bottleneckFarm gives the start width neighbours, each at the head of a
+// chain of depth rooms, with rungs between neighbouring chains; all the
+// chains meet in one room, the only neighbour of the end
+func bottleneckFarm(width, depth int) *Farm {
+	f := newFarm()
+	f.Ants = 100
+	start := f.addRoom("s", 0, 0)
+	hub := f.addRoom("h", depth+1, 0)
+	end := f.addRoom("e", depth+2, 0)
+	f.Start, f.End = "s", "e"
+	f.addTunnel(hub, end)
+	var prev []*Room
+	for i := 0; i < width; i++ {
+		var chain []*Room
+		for k := 0; k < depth; k++ {
+			chain = append(chain, f.addRoom(fmt.Sprintf("r%d_%d", i, k), k+1, i+1))
+		}
+		f.addTunnel(start, chain[0])
+		for k := 1; k < depth; k++ {
+			f.addTunnel(chain[k-1], chain[k])
+		}
+		f.addTunnel(chain[depth-1], hub)
+		for k := range prev {
+			f.addTunnel(prev[k], chain[k])
+		}
+		prev = chain
+	}
+	return f
+}
+
+func BenchmarkBottleneckForward(b *testing.B) {
+	f := bottleneckFarm(200, 20)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if paths, _ := disjointPaths(findNonOverlappingPaths(f)); len(paths) != 1 {
+			b.Fatalf("%d paths, the end allows 1", len(paths))
+		}
+	}
+}
+
+func BenchmarkBottleneckBackward(b *testing.B) {
+	f := bottleneckFarm(200, 20)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if paths, _ := disjointPaths(backwardPaths(f)); len(paths) != 1 {
+			b.Fatalf("%d paths, the end allows 1", len(paths))
+		}
+	}
+}
+
+// What the default strategy pays: the forward search, then the backward
+// one that preferBackward tries because the end is the bottleneck
+func BenchmarkBottleneckPreferBackward(b *testing.B) {
+	f := bottleneckFarm(200, 20)
+	if !endIsBottleneck(f) {
+		b.Fatal("the end is not the bottleneck")
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		forward, _ := disjointPaths(findNonOverlappingPaths(f))
+		if paths, _, _ := preferBackward(f, forward); len(paths) != 1 {
+			b.Fatalf("%d paths, the end allows 1", len(paths))
+		}
+	}
+}
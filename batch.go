@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// A reference binary still running after this long fails its map
+const referenceTimeout = time.Minute
+
+// ----- batch: solve many maps in parallel -----
+// lem-in batch [--workers N] [--algo name] [--cache N [--cache-dir dir]] map1.txt map2.txt ...
+// Results are printed in the order the maps were given. A map that makes
+// the solver panic is reported as an internal error for that map only.
+// With LEMIN_REFERENCE set to another lem-in binary, every map is also
+// solved by it, and a map where we need more turns counts as a failure.
+// With --cache, maps already solved are not solved again (see cache.go).
+func runBatch(args []string) {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	workers := fs.Int("workers", envIntDefault("LEMIN_WORKERS", runtime.GOMAXPROCS(0)), "number of maps solved at the same time")
+	algo := fs.String("algo", envDefault("LEMIN_ALGO", "auto"), "path-finding strategy")
+	cacheSize := fs.Int("cache", 0, "keep the results of this many maps in memory, to solve repeated maps once (0: no cache)")
+	cacheDir := fs.String("cache-dir", "", "with --cache, also keep every result in this directory for later runs")
+	files, _ := parseFlags(fs, args)
+	if len(files) == 0 {
+		fmt.Println("Usage: go run . batch [--workers N] [--algo name] [--cache N [--cache-dir dir]] map1.txt map2.txt ...")
+		return
+	}
+	if *cacheDir != "" && *cacheSize <= 0 {
+		reportErrorf("--cache-dir needs --cache N")
+		return
+	}
+	var cache *solveCache
+	if *cacheSize > 0 {
+		if *cacheDir != "" {
+			if err := os.MkdirAll(*cacheDir, 0o755); err != nil {
+				reportError(err)
+				return
+			}
+		}
+		cache = newSolveCache(*cacheSize, *cacheDir)
+	}
+	strategy, err := lookupStrategy(*algo)
+	if err != nil {
+		reportError(err)
+		return
+	}
+	if *workers < 1 {
+		*workers = 1
+	}
+	reference := os.Getenv("LEMIN_REFERENCE")
+
+	results := make([]batchResult, len(files))
+	done := make([]chan struct{}, len(files))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	live := newLiveTable(len(files))
+	jobs := make(chan int)
+	for w := 0; w < *workers; w++ {
+		go func() {
+			for i := range jobs {
+				live.started(files[i])
+				results[i] = solveBatchMap(files[i], strategy, *algo, cache)
+				if reference != "" && results[i].err == nil {
+					results[i].refTurns, results[i].err = referenceTurns(reference, files[i])
+				}
+				live.finished(files[i], results[i].turns, results[i].err == nil)
+				close(done[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range files {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	failed := 0
+	for i, file := range files {
+		<-done[i]
+		live.hide()
+		if !printBatchResult(file, results[i], reference != "") {
+			failed++
+		}
+		live.show()
+	}
+	live.close()
+	if cache != nil {
+		fmt.Fprintf(os.Stderr, "Cache: %d hits, %d misses\n", cache.hits.Load(), cache.misses.Load())
+	}
+	if failed > 0 {
+		fmt.Printf("%d of %d maps failed\n", failed, len(files))
+		os.Exit(1)
+	}
+}
+
+// printBatchResult prints the line of one map and tells whether it passed
+func printBatchResult(file string, r batchResult, withReference bool) bool {
+	if r.err != nil {
+		fmt.Printf("%s: error: %v\n", file, r.err)
+		return false
+	}
+	if !withReference {
+		fmt.Printf("%s: %d turns, %d paths\n", file, r.turns, r.paths)
+		return true
+	}
+	fmt.Printf("%s: %d turns, %d paths (reference: %d turns)\n", file, r.turns, r.paths, r.refTurns)
+	if r.turns > r.refTurns {
+		fmt.Printf("%s: %d turns more than the reference\n", file, r.turns-r.refTurns)
+		return false
+	}
+	return true
+}
+
+type batchResult struct {
+	turns    int
+	paths    int
+	refTurns int // turns of the LEMIN_REFERENCE binary
+	err      error
+}
+
+func solveBatchMap(filename string, strategy Strategy, algo string, cache *solveCache) (result batchResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := fmt.Errorf("internal error: %v", r)
+			if report, werr := writeCrashReport(filename, "batch", r, debug.Stack()); werr == nil {
+				err = fmt.Errorf("internal error: %v (report: %s)", r, report)
+			}
+			result = batchResult{err: err}
+		}
+	}()
+	farm, err := parseInput(filename, ParseOptions{})
+	if err != nil {
+		return batchResult{err: err}
+	}
+	var key string
+	if cache != nil {
+		key = cacheKey(farm, algo)
+		if r, ok := cache.get(key); ok {
+			return r
+		}
+	}
+	paths, turns, err := solveQuietly(farm, strategy)
+	result = batchResult{turns: turns, paths: len(paths), err: err}
+	if cache != nil {
+		cache.put(key, result)
+	}
+	return result
+}
+
+// referenceTurns runs another lem-in on the map and counts the lines of
+// moves it prints, skipping the map it may echo first.
+func referenceTurns(binary, filename string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), referenceTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, binary, filename).Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return 0, fmt.Errorf("reference %s: no answer within %v", binary, referenceTimeout)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reference %s: %v", binary, err)
+	}
+	turns := 0
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "L") && strings.Contains(line, "-") {
+			turns++
+		}
+	}
+	if turns == 0 {
+		return 0, fmt.Errorf("reference %s: no moves in its output", binary)
+	}
+	return turns, nil
+}
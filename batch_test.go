@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Set LEMIN_REFERENCE to another lem-in binary to compare the turns with
+func TestReference(t *testing.T) {
+	reference := os.Getenv("LEMIN_REFERENCE")
+	if reference == "" {
+		t.Skip("LEMIN_REFERENCE is not set")
+	}
+	for _, name := range []string{"ex1", "ex2", "ex3", "zones", "share", "dash", "cross", "dup"} {
+		_, turns, err := solveQuietly(loadTestMap(t, name), autoPaths)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		theirs, err := referenceTurns(reference, filepath.Join("testdata", "valid", name+".txt"))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if turns > theirs {
+			t.Errorf("%s: %d turns, the reference needs %d", name, turns, theirs)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ----- Binary farm format (.lmb) -----
+// A parsed farm stored with encoding/gob behind a 4-byte magic: rooms are
+// listed in input order and their links refer to rooms by index, so loading
+// skips all the checks and name lookups of the text parser. Links keep
+// their order, which the path-finding tie-breaks depend on.
+const binaryMagic = "LMB1"
+
+type farmBinary struct {
+	Ants       int
+	Start, End int
+	Names      []string
+	X, Y       []int
+	Degrees    []int32 // number of links of each room
+	Links      []int32 // room indices, Degrees[i] of them for room i
+	Zones      []Zone
+	Spawns     []Spawn
+}
+
+func isBinaryFarm(filename string) bool {
+	return strings.HasSuffix(filename, ".lmb")
+}
+
+func writeBinaryFarm(w io.Writer, f *Farm) error {
+	doc := farmBinary{
+		Ants:   f.Ants,
+		Start:  f.Rooms[f.Start].ID,
+		End:    f.Rooms[f.End].ID,
+		Names:  f.Names,
+		Zones:  f.Zones,
+		Spawns: f.Spawns,
+	}
+	for _, name := range f.Names {
+		room := f.Rooms[name]
+		doc.X = append(doc.X, room.X)
+		doc.Y = append(doc.Y, room.Y)
+		doc.Degrees = append(doc.Degrees, int32(f.Degree(name)))
+		for _, link := range room.Links {
+			doc.Links = append(doc.Links, int32(f.Rooms[link].ID))
+		}
+	}
+	if _, err := io.WriteString(w, binaryMagic); err != nil {
+		return err
+	}
+	return gob.NewEncoder(w).Encode(doc)
+}
+
+// loadBinaryFarm reads a farm written by convert. A file can be edited or
+// made by other tools, so the farm goes through the checks of the text
+// parser again, with the same error codes, and names are put in NFC.
+func loadBinaryFarm(filename string, opts ParseOptions) (*Farm, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	limits := opts.Limits.withDefaults()
+	if err := checkFileSize(file, limits); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(file)
+	magic := make([]byte, len(binaryMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != binaryMagic {
+		return nil, fmt.Errorf("%s is not a binary farm file", filename)
+	}
+	var doc farmBinary
+	if err := gob.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid binary farm %s: %v", filename, err)
+	}
+	rooms := len(doc.Names)
+	if len(doc.X) != rooms || len(doc.Y) != rooms || len(doc.Degrees) != rooms ||
+		doc.Start < 0 || doc.Start >= rooms || doc.End < 0 || doc.End >= rooms {
+		return nil, fmt.Errorf("invalid binary farm %s: inconsistent room data", filename)
+	}
+	switch {
+	case rooms > limits.MaxRooms:
+		return nil, codedErrorf(codeTooManyRooms, limits.MaxRooms)
+	case len(doc.Links)/2 > limits.MaxTunnels:
+		return nil, codedErrorf(codeTooManyTunnels, limits.MaxTunnels)
+	}
+	if doc.Ants < 1 && !(doc.Ants == 0 && opts.AllowZeroAnts && !opts.Strict) {
+		return nil, codedErrorf(codeAnts, strconv.Itoa(doc.Ants))
+	}
+	if doc.Start == doc.End {
+		return nil, codedErrorf(codeNoStartEnd)
+	}
+
+	farm := newFarm()
+	farm.Ants = doc.Ants
+	farm.Zones = doc.Zones
+	coords := make(map[[2]int]bool)
+	for i, name := range doc.Names {
+		name = nfc(name)
+		if err := checkRoomName(name, opts); err != nil {
+			return nil, err
+		}
+		if farm.Rooms[name] != nil {
+			return nil, codedErrorf(codeDuplicateRoom, name)
+		}
+		if coords[[2]int{doc.X[i], doc.Y[i]}] {
+			return nil, codedErrorf(codeDuplicateCoords, doc.X[i], doc.Y[i])
+		}
+		coords[[2]int{doc.X[i], doc.Y[i]}] = true
+		farm.addRoom(name, doc.X[i], doc.Y[i])
+	}
+	// Every link must be listed from both ends, as often from each
+	directed := make(map[[2]int32]int, len(doc.Links))
+	links := doc.Links
+	for i, name := range farm.Names {
+		n := int(doc.Degrees[i])
+		if n < 0 || n > len(links) {
+			return nil, fmt.Errorf("invalid binary farm %s: inconsistent link data", filename)
+		}
+		room := farm.Rooms[name]
+		room.Links = make([]string, n)
+		farm.degrees[i] = n
+		farm.stats = nil
+		for k, id := range links[:n] {
+			if id < 0 || int(id) >= rooms {
+				return nil, fmt.Errorf("invalid binary farm %s: link to unknown room", filename)
+			}
+			if int(id) == i {
+				return nil, codedErrorf(codeSelfTunnel, name)
+			}
+			directed[[2]int32{int32(i), id}]++
+			room.Links[k] = farm.Names[id]
+			farm.tunnels = append(farm.tunnels, newTunnel(name, room.Links[k]))
+		}
+		links = links[n:]
+	}
+	for link, count := range directed {
+		if directed[[2]int32{link[1], link[0]}] != count {
+			return nil, fmt.Errorf("invalid binary farm %s: inconsistent link data", filename)
+		}
+	}
+	farm.Start, farm.End = farm.Names[doc.Start], farm.Names[doc.End]
+	// Strict mode ignores ##spawn lines, and so their binary form
+	if !opts.Strict {
+		for _, spawn := range doc.Spawns {
+			if spawn.Ants < 1 || spawn.Turn < 1 {
+				return nil, codedErrorf(codeSpawnLine, fmt.Sprintf("##spawn %d ants at turn %d", spawn.Ants, spawn.Turn))
+			}
+		}
+		farm.Spawns = doc.Spawns
+	}
+	for _, zone := range farm.Zones {
+		for i, name := range zone.Rooms {
+			room := farm.Rooms[nfc(name)]
+			if room == nil {
+				return nil, codedErrorf(codeZoneRoom, zone.Name, name)
+			}
+			if room.Zone != "" {
+				return nil, codedErrorf(codeTwoZones, room.Name, room.Zone, zone.Name)
+			}
+			room.Zone = zone.Name
+			zone.Rooms[i] = room.Name
+		}
+	}
+	for _, name := range []string{farm.Start, farm.End} {
+		if farm.Degree(name) == 0 {
+			return nil, noPathError(codeNoTunnels, name)
+		}
+	}
+	farm.Tunnels()
+	return farm, nil
+}
+
+// ----- convert: rewrite a map in another format -----
+// lem-in convert [--to format] [--nodes nodes.csv] input output
+// Without --to the output format follows its extension: .lmb for the
+// binary format, the text format otherwise. The CSV formats write the
+// rooms to a separate node table, next to the output by default.
+func runConvert(args []string) {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	to := fs.String("to", "", "output format: text, lmb, edgelist, adjmatrix or dot")
+	withCut := fs.Bool("min-cut", false, "with --to dot, highlight the minimum cut between start and end")
+	nodes := fs.String("nodes", "", "node table for edgelist and adjmatrix (default: output name with .nodes.csv)")
+	from := fs.String("from", "text", "input format: text or edgelist (with --nodes and --ants)")
+	ants := fs.Int("ants", -1, "number of ants, required for an edgelist input")
+	args, _ = parseFlags(fs, args)
+	if len(args) != 2 {
+		fmt.Println("Usage: go run . convert [--from text|edgelist] [--to text|lmb|edgelist|adjmatrix|dot [--min-cut]] [--nodes nodes.csv] [--ants N] input output")
+		return
+	}
+	format := *to
+	if format == "" {
+		format = "text"
+		if isBinaryFarm(args[1]) {
+			format = "lmb"
+		}
+	}
+	var write func(io.Writer, *Farm) error
+	switch format {
+	case "text":
+		write = func(w io.Writer, f *Farm) error {
+			_, err := io.WriteString(w, f.String())
+			return err
+		}
+	case "lmb":
+		write = writeBinaryFarm
+	case "edgelist":
+		write = writeEdgeList
+	case "adjmatrix":
+		write = writeAdjacencyMatrix
+	case "dot":
+		write = dotWriter(*withCut)
+	default:
+		reportErrorf("unknown format %q", format)
+		return
+	}
+
+	if *withCut && format != "dot" {
+		reportErrorf("--min-cut needs --to dot")
+		return
+	}
+	if *from == "edgelist" && *ants < 0 {
+		reportErrorf("an edge list has no ant count: set it with --ants")
+		return
+	}
+	inputNodes := ""
+	if *from == "edgelist" {
+		inputNodes, *nodes = *nodes, ""
+	}
+	farm, err := loadFarm(args[0], *from, inputNodes, ParseOptions{AllowZeroAnts: *ants >= 0})
+	if err != nil {
+		reportError(err)
+		return
+	}
+	if *ants >= 0 {
+		farm.Ants = *ants
+	}
+	err = writeFile(args[1], farm, write)
+	if err == nil && (format == "edgelist" || format == "adjmatrix") {
+		if *nodes == "" {
+			*nodes = strings.TrimSuffix(args[1], filepath.Ext(args[1])) + ".nodes.csv"
+		}
+		err = writeFile(*nodes, farm, writeNodeTable)
+	}
+	if err != nil {
+		reportError(err)
+	}
+}
+
+func writeFile(filename string, f *Farm, write func(io.Writer, *Farm) error) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(file)
+	err = write(out, f)
+	if err == nil {
+		err = out.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeBinaryDoc(t *testing.T, doc farmBinary) string {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString(binaryMagic)
+	if err := gob.NewEncoder(&buf).Encode(doc); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "farm.lmb")
+	if err := os.WriteFile(file, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// s-a-e, with one change per test
+func binaryDoc(edit func(doc *farmBinary)) farmBinary {
+	doc := farmBinary{
+		Ants:    2,
+		Start:   0,
+		End:     2,
+		Names:   []string{"s", "a", "e"},
+		X:       []int{0, 1, 2},
+		Y:       []int{0, 0, 0},
+		Degrees: []int32{1, 2, 1},
+		Links:   []int32{1, 0, 2, 1},
+	}
+	edit(&doc)
+	return doc
+}
+
+func TestLoadBinaryFarmChecks(t *testing.T) {
+	tests := []struct {
+		name   string
+		edit   func(doc *farmBinary)
+		strict bool
+		code   string
+	}{
+		{"valid", func(doc *farmBinary) {}, false, ""},
+		{"no ants", func(doc *farmBinary) { doc.Ants = 0 }, false, codeAnts},
+		{"duplicate name", func(doc *farmBinary) { doc.Names[1] = "s" }, false, codeDuplicateRoom},
+		{"decomposed duplicate", func(doc *farmBinary) { doc.Names[0], doc.Names[1] = cafeComposed, cafeDecomposed }, false, codeDuplicateRoom},
+		{"duplicate coordinates", func(doc *farmBinary) { doc.X[1] = 0 }, false, codeDuplicateCoords},
+		{"unprintable name", func(doc *farmBinary) { doc.Names[1] = "a\tb" }, false, codeNameUnprintable},
+		{"non-ascii in strict mode", func(doc *farmBinary) { doc.Names[1] = cafeComposed }, true, codeNameASCII},
+		{"start is end", func(doc *farmBinary) { doc.End = 0 }, false, codeNoStartEnd},
+		{"tunnel to itself", func(doc *farmBinary) { doc.Links[1] = 1 }, false, codeSelfTunnel},
+		{"end without tunnels", func(doc *farmBinary) {
+			doc.Degrees = []int32{1, 1, 0}
+			doc.Links = []int32{1, 0}
+		}, false, codeNoTunnels},
+		{"unknown zone room", func(doc *farmBinary) { doc.Zones = []Zone{{Name: "z", Rooms: []string{"x"}}} }, false, codeZoneRoom},
+	}
+	for _, tt := range tests {
+		file := writeBinaryDoc(t, binaryDoc(tt.edit))
+		_, err := loadBinaryFarm(file, ParseOptions{Strict: tt.strict})
+		if errorCode(err) != tt.code || (tt.code == "" && err != nil) {
+			t.Errorf("%s: got %v, want code %q", tt.name, err, tt.code)
+		}
+	}
+
+	// A link listed from one end only
+	file := writeBinaryDoc(t, binaryDoc(func(doc *farmBinary) {
+		doc.Degrees = []int32{2, 2, 1}
+		doc.Links = []int32{1, 2, 0, 2, 1}
+	}))
+	if _, err := loadBinaryFarm(file, ParseOptions{}); err == nil {
+		t.Error("a one-way link was accepted")
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ----- Cache of solved maps (batch --cache N [--cache-dir dir]) -----
+// Results are keyed by the farm without its ant count, the ant count, the
+// strategy and the build, so the same map in two files or two runs is
+// only solved once. The cache keeps the N most recently used results in
+// memory; with a directory it also keeps every result there, one small
+// file each, to be found again by later runs.
+type solveCache struct {
+	mu      sync.Mutex
+	size    int
+	dir     string
+	order   *list.List // most recently used first, of *cacheEntry
+	entries map[string]*list.Element
+
+	hits, misses atomic.Int64
+}
+
+type cacheEntry struct {
+	key    string
+	result batchResult
+}
+
+func newSolveCache(size int, dir string) *solveCache {
+	return &solveCache{size: size, dir: dir, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Another build of lem-in may solve a map differently, so keys also hash
+// the commit the binary was built from. cacheVersion is for builds without
+// VCS information: raise it with any change to the results.
+const cacheVersion = 1
+
+var (
+	cacheBuildOnce sync.Once
+	cacheBuild     string
+)
+
+// cacheKey hashes the build and the farm as written by String without its
+// ant count, then adds the ant count and the strategy as they are
+func cacheKey(f *Farm, algo string) string {
+	cacheBuildOnce.Do(func() {
+		v := buildVersion()
+		cacheBuild = fmt.Sprintf("lem-in cache %d, commit %s, modified %v\n", cacheVersion, v.Commit, v.Modified)
+	})
+	text := f.String()
+	text = text[strings.IndexByte(text, '\n')+1:]
+	sum := sha256.Sum256([]byte(cacheBuild + text))
+	return fmt.Sprintf("%s-%d-%s", hex.EncodeToString(sum[:16]), f.Ants, algo)
+}
+
+func (c *solveCache) get(key string) (batchResult, bool) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return e.Value.(*cacheEntry).result, true
+	}
+	c.mu.Unlock()
+	if c.dir != "" {
+		var r batchResult
+		data, err := os.ReadFile(c.file(key))
+		if err == nil {
+			if _, err := fmt.Sscanf(string(data), "%d %d", &r.turns, &r.paths); err == nil {
+				c.add(key, r)
+				c.hits.Add(1)
+				return r, true
+			}
+		}
+	}
+	c.misses.Add(1)
+	return batchResult{}, false
+}
+
+// put keeps a result; errors are never cached. A cache directory that
+// cannot be written to only costs the disk copy.
+func (c *solveCache) put(key string, r batchResult) {
+	if r.err != nil {
+		return
+	}
+	c.add(key, r)
+	if c.dir != "" {
+		os.WriteFile(c.file(key), []byte(fmt.Sprintf("%d %d\n", r.turns, r.paths)), 0o644)
+	}
+}
+
+func (c *solveCache) add(key string, r batchResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, result: r})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *solveCache) file(key string) string {
+	return filepath.Join(c.dir, key+".txt")
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ----- Bar chart of the paths (--chart file.svg) -----
+// Two panels of bars, one bar per path in the order of the output: the
+// length of each path in tunnels, then the number of ants sent along it.
+// Plain SVG, so it can go into a report as it is.
+const (
+	chartBarWidth = 28
+	chartGap      = 12
+	chartHeight   = 160 // of the tallest bar in a panel
+	chartMargin   = 40
+	chartMinWidth = 240 // room for the panel titles
+)
+
+func writeChart(filename string, paths [][]string, distribution [][]int) error {
+	lengths := make([]int, len(paths))
+	ants := make([]int, len(paths))
+	for i, p := range paths {
+		lengths[i] = len(p) - 1
+		if i < len(distribution) {
+			ants[i] = len(distribution[i])
+		}
+	}
+	width := 2*chartMargin + len(paths)*(chartBarWidth+chartGap)
+	if width < chartMinWidth {
+		width = chartMinWidth
+	}
+	panel := chartHeight + 2*chartMargin
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-family=\"sans-serif\" font-size=\"12\">\n", width, 2*panel)
+	fmt.Fprintf(&sb, "<rect width=\"%d\" height=\"%d\" fill=\"white\"/>\n", width, 2*panel)
+	chartPanel(&sb, 0, "Path length (tunnels)", lengths, "steelblue")
+	chartPanel(&sb, panel, "Ants per path", ants, "darkorange")
+	sb.WriteString("</svg>\n")
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(file, sb.String())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// chartPanel draws one panel of bars, top being its upper edge
+func chartPanel(sb *strings.Builder, top int, title string, values []int, color string) {
+	highest := 1
+	for _, v := range values {
+		if v > highest {
+			highest = v
+		}
+	}
+	base := top + chartMargin + chartHeight
+	fmt.Fprintf(sb, "<text x=\"%d\" y=\"%d\" font-weight=\"bold\">%s</text>\n", chartMargin, top+chartMargin/2, title)
+	fmt.Fprintf(sb, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"black\"/>\n",
+		chartMargin, base, chartMargin+len(values)*(chartBarWidth+chartGap), base)
+	for i, v := range values {
+		x := chartMargin + chartGap/2 + i*(chartBarWidth+chartGap)
+		h := v * chartHeight / highest
+		fmt.Fprintf(sb, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"/>\n", x, base-h, chartBarWidth, h, color)
+		fmt.Fprintf(sb, "<text x=\"%d\" y=\"%d\" text-anchor=\"middle\">%d</text>\n", x+chartBarWidth/2, base-h-4, v)
+		fmt.Fprintf(sb, "<text x=\"%d\" y=\"%d\" text-anchor=\"middle\">%d</text>\n", x+chartBarWidth/2, base+14, i+1)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import "fmt"
+
+// ----- Check that a solution follows the rules -----
+// Every ant starts in the start room and must end in the end room; on each
+// turn an ant moves at most once, through an existing tunnel, each tunnel
+// is used at most once and no room other than start and end ends the turn
+// holding more than one ant.
+func checkSolution(s Solution) error {
+	f := s.Farm
+	tunnels := make(map[Tunnel]bool)
+	for _, t := range f.Tunnels() {
+		tunnels[t] = true
+	}
+
+	position := make([]string, f.Ants+1)
+	for ant := 1; ant <= f.Ants; ant++ {
+		position[ant] = f.Start
+	}
+	occupant := make(map[string]int)
+
+	for i, turn := range s.Turns {
+		if len(turn) == 0 {
+			return fmt.Errorf("turn %d: no ant moves", i+1)
+		}
+		moved := make(map[int]bool)
+		usedTunnels := make(map[Tunnel]bool)
+		for _, m := range turn {
+			if m.Ant < 1 || m.Ant > f.Ants {
+				return fmt.Errorf("turn %d: unknown ant L%d", i+1, m.Ant)
+			}
+			if moved[m.Ant] {
+				return fmt.Errorf("turn %d: ant L%d moves twice", i+1, m.Ant)
+			}
+			moved[m.Ant] = true
+			from := position[m.Ant]
+			if from == f.End {
+				return fmt.Errorf("turn %d: ant L%d moves after reaching the end", i+1, m.Ant)
+			}
+			tunnel := newTunnel(from, m.Room)
+			if !tunnels[tunnel] {
+				return fmt.Errorf("turn %d: no tunnel %s-%s for ant L%d", i+1, from, m.Room, m.Ant)
+			}
+			if usedTunnels[tunnel] {
+				return fmt.Errorf("turn %d: tunnel %s-%s used twice", i+1, from, m.Room)
+			}
+			usedTunnels[tunnel] = true
+			if occupant[from] == m.Ant {
+				delete(occupant, from)
+			}
+			position[m.Ant] = m.Room
+		}
+		// Rooms are checked once the whole turn has been played, so an ant
+		// may enter a room that another ant leaves during the same turn
+		for _, m := range turn {
+			if m.Room == f.Start || m.Room == f.End {
+				continue
+			}
+			if other, busy := occupant[m.Room]; busy && other != m.Ant {
+				return fmt.Errorf("turn %d: ants L%d and L%d both in room %s", i+1, other, m.Ant, m.Room)
+			}
+			occupant[m.Room] = m.Ant
+		}
+	}
+
+	for ant := 1; ant <= f.Ants; ant++ {
+		if position[ant] != f.End {
+			return fmt.Errorf("ant L%d never reaches the end", ant)
+		}
+	}
+	return nil
+}
+
+// ----- Compare solutions that may legitimately differ -----
+// Two solutions are equivalent when both are legal for the same farm and
+// take the same number of turns, even if they use different paths or move
+// the ants in a different order. Meant for golden tests, where an exact
+// comparison would break on any legitimate change of ordering.
+func EquivalentSolutions(a, b Solution) bool {
+	if !sameLayout(a.Farm, b.Farm) || len(a.Turns) != len(b.Turns) {
+		return false
+	}
+	return checkSolution(a) == nil && checkSolution(b) == nil
+}
+
+// sameLayout tells whether two farms have the same ants, start, end, rooms
+// and tunnels, in any order; unlike sameFarm it ignores coordinates and
+// zones, which do not change what a solution may do
+func sameLayout(a, b *Farm) bool {
+	if a == b {
+		return true
+	}
+	if a.Ants != b.Ants || a.Start != b.Start || a.End != b.End || len(a.Rooms) != len(b.Rooms) {
+		return false
+	}
+	for name := range a.Rooms {
+		if b.Rooms[name] == nil {
+			return false
+		}
+	}
+	ta, tb := a.Tunnels(), b.Tunnels()
+	if len(ta) != len(tb) {
+		return false
+	}
+	for i := range ta {
+		if ta[i] != tb[i] {
+			return false
+		}
+	}
+	return true
+}
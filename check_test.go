@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestEquivalentSolutions(t *testing.T) {
+	s := solveMap(t, "ex1")
+	other := s
+	other.Farm = loadTestMap(t, "ex1")
+	if !EquivalentSolutions(s, other) {
+		t.Error("the same solution on a new parse of the map is not equivalent")
+	}
+
+	// Legal on both farms, but the farms differ
+	wider := s
+	wider.Farm = s.Farm.Clone()
+	wider.Farm.addTunnel(wider.Farm.Rooms[wider.Farm.Start], wider.Farm.Rooms[wider.Farm.End])
+	if err := checkSolution(wider); err != nil {
+		t.Fatal(err)
+	}
+	if EquivalentSolutions(s, wider) {
+		t.Error("solutions for farms with different tunnels are equivalent")
+	}
+
+	shorter := s
+	shorter.Turns = s.Turns[:len(s.Turns)-1]
+	if EquivalentSolutions(s, shorter) {
+		t.Error("solutions with different turn counts are equivalent")
+	}
+}
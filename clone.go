@@ -0,0 +1,76 @@
+package main
+
+// ----- Copies of a farm -----
+// A parsed farm is read-only: the strategies, the simulator and the
+// reports only read it, so one farm can be solved by several goroutines
+// at once (batch, bench). Code that needs to edit a farm works on a Clone.
+
+// Clone returns a deep copy of the farm, with the same rooms, tunnels in
+// the same order (the searches depend on it) and zones.
+func (f *Farm) Clone() *Farm {
+	c := newFarm()
+	c.Ants, c.Start, c.End = f.Ants, f.Start, f.End
+	c.Spawns = append(c.Spawns, f.Spawns...)
+	for _, name := range f.Names {
+		room := f.Rooms[name]
+		copied := c.addRoom(name, room.X, room.Y)
+		copied.Zone = room.Zone
+	}
+	for _, name := range c.Names {
+		// Point the links at the clone's own interned names
+		room, links := c.Rooms[name], f.Rooms[name].Links
+		room.Links = make([]string, len(links))
+		for i, link := range links {
+			room.Links[i] = c.Rooms[link].Name
+		}
+	}
+	c.degrees = append([]int(nil), f.degrees...)
+	c.tunnels, c.tunnelsSorted = append([]Tunnel(nil), f.Tunnels()...), true
+	for _, zone := range f.Zones {
+		c.Zones = append(c.Zones, Zone{Name: zone.Name, Rooms: append([]string(nil), zone.Rooms...)})
+	}
+	return c
+}
+
+// sameFarm tells whether two farms have the same rooms, tunnels (in the
+// same order) and zones; the self-check uses it to catch a strategy that
+// edited the farm it was given.
+func sameFarm(a, b *Farm) bool {
+	if a.Ants != b.Ants || a.Start != b.Start || a.End != b.End ||
+		!sameStrings(a.Names, b.Names) || len(a.Rooms) != len(b.Rooms) {
+		return false
+	}
+	for name, room := range a.Rooms {
+		other := b.Rooms[name]
+		if other == nil || room.ID != other.ID || room.X != other.X || room.Y != other.Y ||
+			room.Zone != other.Zone || !sameStrings(room.Links, other.Links) {
+			return false
+		}
+	}
+	if len(a.Spawns) != len(b.Spawns) || len(a.Zones) != len(b.Zones) {
+		return false
+	}
+	for i, spawn := range a.Spawns {
+		if spawn != b.Spawns[i] {
+			return false
+		}
+	}
+	for i, zone := range a.Zones {
+		if zone.Name != b.Zones[i].Name || !sameStrings(zone.Rooms, b.Zones[i].Rooms) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
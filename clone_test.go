@@ -0,0 +1,53 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+// Run with -race: every strategy solves the same farm at once
+func TestConcurrentStrategies(t *testing.T) {
+	for _, name := range []string{"ex1", "ex3", "zones"} {
+		farm := loadTestMap(t, name)
+		before := farm.Clone()
+		var wg sync.WaitGroup
+		for _, algo := range strategyNames() {
+			for i := 0; i < 4; i++ {
+				strategy, err := lookupStrategy(algo)
+				if err != nil {
+					t.Fatal(err)
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, _, err := solveQuietly(farm, strategy); err != nil {
+						t.Errorf("%s, %s: %v", name, algo, err)
+					}
+					farm.Stats()
+					farm.Tunnels()
+				}()
+			}
+		}
+		wg.Wait()
+		if !sameFarm(farm, before) {
+			t.Errorf("%s: solving changed the farm", name)
+		}
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	farm := loadTestMap(t, "ex3")
+	tunnels := len(farm.Tunnels())
+	c := farm.Clone()
+	if !sameFarm(c, farm) {
+		t.Fatal("the clone differs from the farm")
+	}
+	c.addTunnel(c.Rooms[c.Start], c.Rooms[c.End])
+	c.Rooms[c.Start].X++
+	if sameFarm(c, farm) || farm.Degree(farm.Start) == c.Degree(c.Start) {
+		t.Error("the clone shares rooms or degrees with the farm")
+	}
+	if len(farm.Tunnels()) != tunnels || len(c.Tunnels()) != tunnels+1 {
+		t.Errorf("tunnels: farm %d, clone %d, want %d and %d", len(farm.Tunnels()), len(c.Tunnels()), tunnels, tunnels+1)
+	}
+}
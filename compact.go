@@ -0,0 +1,99 @@
+package main
+
+import "sort"
+
+// ----- Compact a schedule (--compact) -----
+// compactTurns replays every ant over the same rooms, but leaving as early
+// as it can and never waiting on the way: ants are placed one at a time,
+// in the order they first left, on the first turn where none of their
+// rooms and tunnels is already taken (the rules of checkSolution). The
+// result is returned only if it is shorter than turns.
+func compactTurns(f *Farm, turns []Turn) []Turn {
+	// Route, path and departure order of every ant
+	routes := make(map[int][]string)
+	paths := make(map[int]int)
+	var order []int
+	for _, turn := range turns {
+		for _, m := range turn {
+			if routes[m.Ant] == nil {
+				routes[m.Ant] = []string{f.Start}
+				order = append(order, m.Ant)
+			}
+			routes[m.Ant] = append(routes[m.Ant], m.Room)
+			paths[m.Ant] = m.Path
+		}
+	}
+
+	// A room is taken when an ant holds it at the end of the turn, a
+	// tunnel (a, b with a < b) when an ant goes through it
+	type slot struct {
+		a, b string
+		turn int
+	}
+	taken := make(map[slot]bool)
+	tunnel := func(a, b string, turn int) slot {
+		if b < a {
+			a, b = b, a
+		}
+		return slot{a, b, turn}
+	}
+	fits := func(route []string, depart int) bool {
+		for k := 1; k < len(route); k++ {
+			turn := depart + k - 1
+			if taken[tunnel(route[k-1], route[k], turn)] || (route[k] != f.End && taken[slot{route[k], "", turn}]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Ants leaving through the same tunnel need not look before the
+	// first turn it is free
+	firstFree := make(map[slot]int)
+	var compacted []Turn
+	for _, ant := range order {
+		route := routes[ant]
+		first := tunnel(route[0], route[1], 0)
+		depart := firstFree[first]
+		if depart == 0 {
+			depart = 1
+		}
+		for !fits(route, depart) {
+			depart++
+		}
+		for k := 1; k < len(route); k++ {
+			turn := depart + k - 1
+			taken[tunnel(route[k-1], route[k], turn)] = true
+			if route[k] != f.End {
+				taken[slot{route[k], "", turn}] = true
+			}
+			for len(compacted) < turn {
+				compacted = append(compacted, nil)
+			}
+			compacted[turn-1] = append(compacted[turn-1], Move{Ant: ant, Room: route[k], Path: paths[ant]})
+		}
+		next := firstFree[first]
+		if next == 0 {
+			next = 1
+		}
+		for taken[slot{first.a, first.b, next}] {
+			next++
+		}
+		firstFree[first] = next
+	}
+
+	// A turn where nobody moves can be dropped: the turns after it keep
+	// the same positions, one turn earlier
+	kept := compacted[:0]
+	for _, turn := range compacted {
+		if len(turn) > 0 {
+			// Path by path, ants furthest along first, as the simulator writes them
+			sort.SliceStable(turn, func(i, j int) bool { return turn[i].Path < turn[j].Path })
+			kept = append(kept, turn)
+		}
+	}
+	if len(kept) >= len(turns) {
+		return turns
+	}
+	return kept
+}
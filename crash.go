@@ -0,0 +1,62 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Exit code for internal errors (EX_SOFTWARE)
+const exitInternal = 70
+
+// ----- Recover from a panic in the solver phases -----
+// Instead of a raw stack trace the user gets a short message and a report
+// file to attach to a bug report. Deferred by main.
+func recoverInternalError(filename string, prog *progress) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	phase := prog.phase.Load().(string)
+	fmt.Fprintf(os.Stderr, "Internal error during %s: %v\n", phase, r)
+	if report, err := writeCrashReport(filename, phase, r, debug.Stack()); err == nil {
+		fmt.Fprintf(os.Stderr, "A diagnostic report was written to %s, please attach it to your bug report.\n", report)
+	}
+	os.Exit(exitInternal)
+}
+
+// writeCrashReport saves the panic, the phase, the stack and a hash of the
+// map (the map itself may be huge or private) and returns the file path.
+func writeCrashReport(filename, phase string, r interface{}, stack []byte) (string, error) {
+	mapHash := "unavailable"
+	if data, err := os.ReadFile(filename); err == nil {
+		sum := sha256.Sum256(data)
+		mapHash = hex.EncodeToString(sum[:])
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "lem-in internal error\n")
+	fmt.Fprintf(&sb, "map:      %s\n", filename)
+	fmt.Fprintf(&sb, "sha256:   %s\n", mapHash)
+	fmt.Fprintf(&sb, "phase:    %s\n", phase)
+	fmt.Fprintf(&sb, "panic:    %v\n", r)
+	fmt.Fprintf(&sb, "go:       %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&sb, "args:     %s\n\n", strings.Join(os.Args, " "))
+	sb.Write(stack)
+
+	// A new file readable by the user only: the report holds the arguments
+	// and paths, and a fixed name in a shared directory can be planted
+	file, err := os.CreateTemp("", "lem-in-crash-*.txt")
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.WriteString(sb.String()); err != nil {
+		file.Close()
+		return "", err
+	}
+	return file.Name(), file.Close()
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// ----- CSV exports for graph tools (convert --to edgelist|adjmatrix) -----
+// Room names are written as they are, without the quoting of the map
+// format; encoding/csv quotes them when needed.
+
+// writeEdgeList writes one source,target row per tunnel
+func writeEdgeList(w io.Writer, f *Farm) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"source", "target"})
+	for _, t := range f.tunnelList() {
+		out.Write(t[:])
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeAdjacencyMatrix writes a header row of room names, then one row
+// per room with 1 for each room it has a tunnel to
+func writeAdjacencyMatrix(w io.Writer, f *Farm) error {
+	out := csv.NewWriter(w)
+	out.Write(append([]string{""}, f.Names...))
+	row := make([]string, len(f.Names)+1)
+	for _, name := range f.Names {
+		row[0] = name
+		for i := range f.Names {
+			row[i+1] = "0"
+		}
+		for _, link := range f.Rooms[name].Links {
+			row[f.Rooms[link].ID+1] = "1"
+		}
+		out.Write(row)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeNodeTable writes name,x,y,is_start,is_end for every room
+func writeNodeTable(w io.Writer, f *Farm) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"name", "x", "y", "is_start", "is_end"})
+	for _, name := range f.Names {
+		room := f.Rooms[name]
+		out.Write([]string{name, strconv.Itoa(room.X), strconv.Itoa(room.Y),
+			strconv.FormatBool(name == f.Start), strconv.FormatBool(name == f.End)})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// ----- Read an edge list back (--from edgelist) -----
+// The node table gives the start and end rooms and, optionally, the
+// coordinates; rooms without coordinates, or only named in the edge
+// list, are placed on free spots of the x axis. Edge lists hold no ant
+// count: the caller sets farm.Ants.
+func loadEdgeList(edgesFile, nodesFile string, opts ParseOptions) (*Farm, error) {
+	if nodesFile == "" {
+		return nil, fmt.Errorf("an edge list needs a node table (--nodes) for the start and end rooms")
+	}
+	limits := opts.Limits.withDefaults()
+	nodes, err := readCSV(nodesFile, limits)
+	if err != nil {
+		return nil, err
+	}
+	edges, err := readCSV(edgesFile, limits)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 || len(edges) == 0 {
+		return nil, fmt.Errorf("missing header in %s or %s", nodesFile, edgesFile)
+	}
+
+	column := make(map[string]int)
+	for i, name := range nodes[0] {
+		column[name] = i
+	}
+	if _, ok := column["name"]; !ok {
+		return nil, fmt.Errorf("%s has no name column", nodesFile)
+	}
+	field := func(row []string, name string) string {
+		if i, ok := column[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	farm := newFarm()
+	used := make(map[[2]int]bool)
+	var unplaced []*Room
+	addRoom := func(name string) (*Room, error) {
+		if err := checkRoomName(name, opts); err != nil {
+			return nil, err
+		}
+		if len(farm.Names) == limits.MaxRooms {
+			return nil, codedErrorf(codeTooManyRooms, limits.MaxRooms)
+		}
+		room := farm.addRoom(name, 0, 0)
+		unplaced = append(unplaced, room)
+		return room, nil
+	}
+	for _, row := range nodes[1:] {
+		name := field(row, "name")
+		if farm.Rooms[name] != nil {
+			return nil, codedErrorf(codeDuplicateRoom, name)
+		}
+		room, err := addRoom(name)
+		if err != nil {
+			return nil, err
+		}
+		if field(row, "x") != "" || field(row, "y") != "" {
+			x, err1 := strconv.Atoi(field(row, "x"))
+			y, err2 := strconv.Atoi(field(row, "y"))
+			if err1 != nil || err2 != nil {
+				return nil, codedErrorf(codeCoordinates, name)
+			}
+			if used[[2]int{x, y}] {
+				return nil, codedErrorf(codeDuplicateCoords, x, y)
+			}
+			used[[2]int{x, y}] = true
+			room.X, room.Y = x, y
+			unplaced = unplaced[:len(unplaced)-1]
+		}
+		for _, end := range []struct {
+			column string
+			room   *string
+		}{{"is_start", &farm.Start}, {"is_end", &farm.End}} {
+			if set, _ := strconv.ParseBool(field(row, end.column)); set {
+				if *end.room != "" {
+					return nil, fmt.Errorf("more than one room marked %s in %s", end.column, nodesFile)
+				}
+				*end.room = room.Name
+			}
+		}
+	}
+
+	if len(edges)-1 > limits.MaxTunnels {
+		return nil, codedErrorf(codeTooManyTunnels, limits.MaxTunnels)
+	}
+	for _, row := range edges[1:] {
+		if len(row) < 2 {
+			return nil, fmt.Errorf("invalid edge in %s: %q", edgesFile, row)
+		}
+		var ends [2]*Room
+		for i, name := range row[:2] {
+			if ends[i] = farm.Rooms[name]; ends[i] == nil {
+				if ends[i], err = addRoom(name); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if ends[0] == ends[1] {
+			return nil, codedErrorf(codeSelfTunnel, ends[0].Name)
+		}
+		farm.addTunnel(ends[0], ends[1])
+	}
+
+	for x, i := 0, 0; i < len(unplaced); x++ {
+		if !used[[2]int{x, 0}] {
+			unplaced[i].X = x
+			i++
+		}
+	}
+	if farm.Start == "" || farm.End == "" {
+		return nil, codedErrorf(codeNoStartEnd)
+	}
+	for _, name := range []string{farm.Start, farm.End} {
+		if farm.Degree(name) == 0 {
+			return nil, noPathError(codeNoTunnels, name)
+		}
+	}
+	farm.Tunnels()
+	return farm, nil
+}
+
+func readCSV(filename string, limits Limits) ([][]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if err := checkFileSize(file, limits); err != nil {
+		return nil, err
+	}
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV file %s: %v", filename, err)
+	}
+	for _, record := range records {
+		for i, field := range record {
+			record[i] = nfc(field)
+		}
+	}
+	return records, nil
+}
+
+// loadFarm reads a map in the given input format: text (which includes
+// .lmb files) or edgelist
+func loadFarm(filename, from, nodesFile string, opts ParseOptions) (*Farm, error) {
+	switch from {
+	case "text":
+		return parseInput(filename, opts)
+	case "edgelist":
+		return loadEdgeList(filename, nodesFile, opts)
+	}
+	return nil, fmt.Errorf("unknown input format %q", from)
+}
@@ -0,0 +1,65 @@
+package main
+
+import "sync/atomic"
+
+// ----- Bounded-memory search (--max-frontier) -----
+// maxFrontier caps the BFS queue (0: no cap); set once from the command
+// line before any search runs.
+var maxFrontier int
+
+// frontierFallbacks counts the searches that went over maxFrontier
+var frontierFallbacks atomic.Int64
+
+// iterativeDeepening finds a shortest path from start through
+// startNeighbor to end, avoiding blockedRooms, with depth-limited searches
+// of growing depth. It keeps the current path and the shallowest depth at
+// which each room was reached in this round, so a round costs one pass
+// over the tunnels and memory stays proportional to the rooms.
+func iterativeDeepening(f *Farm, startNeighbor string, blockedRooms map[string]bool) []string {
+	if startNeighbor == f.End {
+		return []string{f.Start, f.End}
+	}
+	for limit := 1; limit <= len(f.Names); limit++ {
+		depth := map[string]int{f.Start: 0, startNeighbor: 1}
+		path := []string{f.Start, startNeighbor}
+		exhausted := true // no room was left out because of the limit
+
+		var search func(room string) bool
+		search = func(room string) bool {
+			if len(path)-1 == limit {
+				exhausted = false
+				return false
+			}
+			for _, next := range f.Rooms[room].Links {
+				if blockedRooms[next] {
+					continue
+				}
+				if next == f.End && len(path) == limit {
+					path = append(path, next)
+					return true
+				}
+				if next == f.End {
+					exhausted = false
+					continue
+				}
+				if d, seen := depth[next]; seen && d <= len(path) {
+					continue
+				}
+				depth[next] = len(path)
+				path = append(path, next)
+				if search(next) {
+					return true
+				}
+				path = path[:len(path)-1]
+			}
+			return false
+		}
+		if search(startNeighbor) {
+			return path
+		}
+		if exhausted {
+			return nil
+		}
+	}
+	return nil
+}
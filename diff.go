@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ----- diff: compare two versions of a map -----
+// lem-in diff old.txt new.txt
+// Lists the rooms and tunnels added or removed and how the edit changes the
+// number of turns.
+func runDiff(args []string) {
+	if len(args) != 2 {
+		fmt.Println("Usage: go run . diff old.txt new.txt")
+		return
+	}
+	before, err := parseInput(args[0], ParseOptions{})
+	if err != nil {
+		reportErrorf("%s: %w", args[0], err)
+		return
+	}
+	after, err := parseInput(args[1], ParseOptions{})
+	if err != nil {
+		reportErrorf("%s: %w", args[1], err)
+		return
+	}
+
+	printSetDiff("Rooms", roomSet(before), roomSet(after))
+	printSetDiff("Tunnels", tunnelSet(before), tunnelSet(after))
+	if before.Start != after.Start || before.End != after.End {
+		fmt.Printf("Start/end: %s/%s -> %s/%s\n", before.Start, before.End, after.Start, after.End)
+	}
+	if before.Ants != after.Ants {
+		fmt.Printf("Ants: %d -> %d\n", before.Ants, after.Ants)
+	}
+
+	turnsBefore, errBefore := diffTurns(before)
+	turnsAfter, errAfter := diffTurns(after)
+	switch {
+	case errBefore != nil || errAfter != nil:
+		fmt.Printf("Turns: %s -> %s\n", turnsOrError(turnsBefore, errBefore), turnsOrError(turnsAfter, errAfter))
+	default:
+		fmt.Printf("Turns: %d -> %d (%+d)\n", turnsBefore, turnsAfter, turnsAfter-turnsBefore)
+	}
+}
+
+func diffTurns(f *Farm) (int, error) {
+	_, turns, err := solveQuietly(f, autoPaths)
+	return turns, err
+}
+
+func turnsOrError(turns int, err error) string {
+	if err != nil {
+		return "error (" + err.Error() + ")"
+	}
+	return fmt.Sprint(turns)
+}
+
+func roomSet(f *Farm) map[string]bool {
+	set := make(map[string]bool, len(f.Names))
+	for _, name := range f.Names {
+		set[name] = true
+	}
+	return set
+}
+
+// tunnelSet keys tunnels by their room names in alphabetical order, as room
+// IDs differ between the two files
+func tunnelSet(f *Farm) map[string]bool {
+	set := make(map[string]bool)
+	for _, t := range f.Tunnels() {
+		set[quoteName(t.A)+"-"+quoteName(t.B)] = true
+	}
+	return set
+}
+
+func printSetDiff(label string, before, after map[string]bool) {
+	var added, removed []string
+	for k := range after {
+		if !before[k] {
+			added = append(added, k)
+		}
+	}
+	for k := range before {
+		if !after[k] {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	fmt.Printf("%s: %d added, %d removed\n", label, len(added), len(removed))
+	for _, k := range added {
+		fmt.Printf("  + %s\n", k)
+	}
+	for _, k := range removed {
+		fmt.Printf("  - %s\n", k)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ----- Graphviz export (convert --to dot [--min-cut]) -----
+// Rooms are placed at their coordinates (neato -n, or fdp), start and end
+// drawn as double circles. With the minimum cut, its rooms are filled in
+// red and its tunnels drawn thick and red, and the graph is labelled with
+// how many disjoint paths the cut allows.
+func dotWriter(withCut bool) func(io.Writer, *Farm) error {
+	return func(w io.Writer, f *Farm) error {
+		var cut farmCut
+		if withCut {
+			cut = minCut(f)
+		}
+		var sb strings.Builder
+		sb.WriteString("graph farm {\n")
+		if withCut {
+			paths := "paths"
+			if cut.size() == 1 {
+				paths = "path"
+			}
+			fmt.Fprintf(&sb, "\tlabel=%q;\n", fmt.Sprintf("at most %d disjoint %s: the minimum cut is in red", cut.size(), paths))
+		}
+		for _, name := range f.Names {
+			room := f.Rooms[name]
+			attrs := []string{fmt.Sprintf("pos=\"%d,%d!\"", room.X, room.Y)}
+			switch name {
+			case f.Start, f.End:
+				attrs = append(attrs, "shape=doublecircle")
+			}
+			if cut.rooms[name] {
+				attrs = append(attrs, "style=filled", "fillcolor=red")
+			}
+			fmt.Fprintf(&sb, "\t%q [%s];\n", name, strings.Join(attrs, ", "))
+		}
+		for _, t := range f.tunnelList() {
+			attrs := ""
+			if cut.tunnels[t] || cut.tunnels[[2]string{t[1], t[0]}] {
+				attrs = " [color=red, penwidth=3]"
+			}
+			fmt.Fprintf(&sb, "\t%q -- %q%s;\n", t[0], t[1], attrs)
+		}
+		sb.WriteString("}\n")
+		_, err := io.WriteString(w, sb.String())
+		return err
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// ----- Output encoder registry (--format) -----
+// An Encoder writes the solution as it is simulated: Begin before the
+// first turn, Turn for every turn and End once the last turn is known.
+// Turns are written through a buffer that is flushed before End.
+type Encoder interface {
+	Begin(w io.Writer, f *Farm) error
+	Turn(w io.Writer, turn Turn) error
+	End(w io.Writer, sol Solution) error
+}
+
+// EncoderOptions are the command line options an encoder may follow
+type EncoderOptions struct {
+	Moves  turnWriter // the text form of a turn, after --group-by and --follow
+	Strict bool       // print the map first, and refuse empty turns
+	Input  []byte     // the map file as read, nil for binary and edge list input
+}
+
+var encoders = make(map[string]func(EncoderOptions) Encoder)
+
+// RegisterEncoder makes an output format selectable with --format. Forks
+// can call it from an init function, like RegisterStrategy.
+func RegisterEncoder(name string, factory func(EncoderOptions) Encoder) {
+	if _, exists := encoders[name]; exists {
+		panic(fmt.Sprintf("encoder %q registered twice", name))
+	}
+	encoders[name] = factory
+}
+
+func init() {
+	RegisterEncoder("text", func(o EncoderOptions) Encoder {
+		if o.Strict {
+			return &textEncoder{strict: true, moves: specTurns(o.Moves)}
+		}
+		return &textEncoder{moves: o.Moves}
+	})
+	RegisterEncoder("json", func(EncoderOptions) Encoder { return &jsonEncoder{} })
+	RegisterEncoder("jsonl", func(EncoderOptions) Encoder { return &jsonlEncoder{write: jsonLines()} })
+	RegisterEncoder("csv", func(EncoderOptions) Encoder { return &csvEncoder{} })
+	RegisterEncoder("visualizer", func(o EncoderOptions) Encoder {
+		return &visualizerEncoder{input: o.Input, moves: specTurns(o.Moves)}
+	})
+}
+
+func lookupEncoder(name string, opts EncoderOptions) (Encoder, error) {
+	factory, ok := encoders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q", name)
+	}
+	return factory(opts), nil
+}
+
+func encoderNames() []string {
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// One line of moves per turn, after the map with --strict
+type textEncoder struct {
+	strict bool
+	moves  turnWriter
+}
+
+func (e *textEncoder) Begin(w io.Writer, f *Farm) error {
+	if e.strict {
+		return writeSpecHeader(w, f)
+	}
+	return nil
+}
+
+func (e *textEncoder) Turn(w io.Writer, turn Turn) error { return e.moves(w, turn) }
+
+func (e *textEncoder) End(io.Writer, Solution) error { return nil }
+
+// The map exactly as read, a blank line, then one line of moves per
+// turn: what the community visualizers read from a pipe
+// (--visualizer-compat). A map that was not read as text is written out
+// as Farm.String does.
+type visualizerEncoder struct {
+	input []byte
+	moves turnWriter
+}
+
+func (e *visualizerEncoder) Begin(w io.Writer, f *Farm) error {
+	input := e.input
+	if input == nil {
+		input = []byte(f.String())
+	}
+	if _, err := w.Write(input); err != nil {
+		return err
+	}
+	blank := "\n"
+	if len(input) > 0 && input[len(input)-1] != '\n' {
+		blank = "\n\n"
+	}
+	_, err := io.WriteString(w, blank)
+	return err
+}
+
+func (e *visualizerEncoder) Turn(w io.Writer, turn Turn) error { return e.moves(w, turn) }
+
+func (e *visualizerEncoder) End(io.Writer, Solution) error { return nil }
+
+// The whole solution as one document, written at the end
+type jsonEncoder struct {
+	turns []Turn
+}
+
+func (e *jsonEncoder) Begin(io.Writer, *Farm) error { return nil }
+
+func (e *jsonEncoder) Turn(_ io.Writer, turn Turn) error {
+	e.turns = append(e.turns, turn)
+	return nil
+}
+
+func (e *jsonEncoder) End(w io.Writer, sol Solution) error {
+	sol.Turns = e.turns
+	return writeSolutionJSON(w, sol)
+}
+
+// One JSON object per turn
+type jsonlEncoder struct {
+	write turnWriter
+}
+
+func (e *jsonlEncoder) Begin(io.Writer, *Farm) error      { return nil }
+func (e *jsonlEncoder) Turn(w io.Writer, turn Turn) error { return e.write(w, turn) }
+func (e *jsonlEncoder) End(io.Writer, Solution) error     { return nil }
+
+// One turn,ant,room row per move, for spreadsheets
+type csvEncoder struct {
+	turn int
+}
+
+func (e *csvEncoder) Begin(w io.Writer, _ *Farm) error {
+	return writeCSVRow(w, "turn", "ant", "room")
+}
+
+func (e *csvEncoder) Turn(w io.Writer, turn Turn) error {
+	e.turn++
+	for _, m := range turn {
+		if err := writeCSVRow(w, strconv.Itoa(e.turn), strconv.Itoa(m.Ant), m.Room); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *csvEncoder) End(io.Writer, Solution) error { return nil }
+
+func writeCSVRow(w io.Writer, fields ...string) error {
+	out := csv.NewWriter(w)
+	out.Write(fields)
+	out.Flush()
+	return out.Error()
+}
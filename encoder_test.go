@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// visualizerOutput encodes a solved map as --visualizer-compat does
+func visualizerOutput(t *testing.T, s Solution, input []byte) string {
+	t.Helper()
+	enc, err := lookupEncoder("visualizer", EncoderOptions{Moves: writeTurn, Input: input})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := enc.Begin(&buf, s.Farm); err != nil {
+		t.Fatal(err)
+	}
+	for _, turn := range s.Turns {
+		if err := enc.Turn(&buf, turn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.End(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// checkVisualizerOutput reads out the way the visualizers do: the map up
+// to the first blank line, then one line of Lant-room moves per turn
+func checkVisualizerOutput(t *testing.T, name, out string, s Solution) {
+	t.Helper()
+	header, moves, ok := strings.Cut(out, "\n\n")
+	if !ok {
+		t.Fatalf("%s: no blank line after the map", name)
+	}
+	file := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(file, []byte(header+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := parseInput(file, ParseOptions{})
+	if err != nil {
+		t.Fatalf("%s: the map does not parse back: %v", name, err)
+	}
+	// Farm.String may list the tunnels in another order
+	if f.Ants != s.Farm.Ants || f.Start != s.Farm.Start || f.End != s.Farm.End ||
+		!reflect.DeepEqual(f.Names, s.Farm.Names) || !reflect.DeepEqual(f.Tunnels(), s.Farm.Tunnels()) {
+		t.Errorf("%s: the map read back differs", name)
+	}
+	lines := strings.Split(strings.TrimSuffix(moves, "\n"), "\n")
+	if len(lines) != len(s.Turns) {
+		t.Errorf("%s: %d lines of moves for %d turns", name, len(lines), len(s.Turns))
+	}
+	arrived := make(map[int]bool)
+	for _, line := range lines {
+		if !specLine.MatchString(line) {
+			t.Errorf("%s: line %q is not in the subject's format", name, line)
+			continue
+		}
+		for _, move := range strings.Fields(line) {
+			ant, room, _ := strings.Cut(move[1:], "-")
+			n, _ := strconv.Atoi(ant)
+			if f.Rooms[room] == nil {
+				t.Errorf("%s: move %s to an unknown room", name, move)
+			}
+			if room == f.End {
+				arrived[n] = true
+			}
+		}
+	}
+	if len(arrived) != f.Ants {
+		t.Errorf("%s: %d of %d ants arrive", name, len(arrived), f.Ants)
+	}
+}
+
+func TestVisualizerEncoder(t *testing.T) {
+	for _, name := range []string{"ex1", "ex2", "dash", "zones"} {
+		s := solveMap(t, name)
+		input, err := os.ReadFile(filepath.Join("testdata", "valid", name+".txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := visualizerOutput(t, s, input)
+		if !strings.HasPrefix(out, string(input)) {
+			t.Errorf("%s: the output does not start with the map as read", name)
+		}
+		checkVisualizerOutput(t, name, out, s)
+
+		// Without the file, as for binary input, and without its last newline
+		checkVisualizerOutput(t, name+" from the farm", visualizerOutput(t, s, nil), s)
+		checkVisualizerOutput(t, name+" without newline", visualizerOutput(t, s, bytes.TrimSuffix(input, []byte("\n"))), s)
+	}
+}
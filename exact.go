@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Maps with more rooms than this are refused by the exact solver
+const defaultExactRooms = 16
+
+// Give up when a map has more simple start-end paths than this
+const maxExactPaths = 100000
+
+// ----- Exact solver for small maps -----
+// Lists every simple path from start to end, then tries every set of paths
+// that share no intermediate room. For a fixed set of paths the balanced
+// distribution is optimal, so the best set gives the true minimum turns.
+func exactMinTurns(f *Farm, maxRooms int) (int, [][]string, error) {
+	if len(f.Rooms) > maxRooms {
+		return 0, nil, fmt.Errorf("map has %d rooms, exact solver is limited to %d", len(f.Rooms), maxRooms)
+	}
+	paths, err := allSimplePaths(f, maxExactPaths)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(paths) == 0 {
+		return 0, nil, fmt.Errorf("no path from %s to %s", f.Start, f.End)
+	}
+	sort.SliceStable(paths, func(i, j int) bool { return len(paths[i]) < len(paths[j]) })
+
+	bestTurns := -1
+	var bestSet [][]string
+	forEachDisjointSet(paths, func(set [][]string) {
+		if turns := predictTurns(set, f.Ants, 0); bestTurns < 0 || turns < bestTurns {
+			bestTurns = turns
+			bestSet = append([][]string(nil), set...)
+		}
+	})
+	return bestTurns, bestSet, nil
+}
+
+// forEachDisjointSet calls fn with every non-empty set of the paths that
+// share no intermediate room. The set is reused between calls.
+func forEachDisjointSet(paths [][]string, fn func(set [][]string)) {
+	var current [][]string
+	used := make(map[string]bool)
+
+	var search func(from int)
+	search = func(from int) {
+		if len(current) > 0 {
+			fn(current)
+		}
+		for i := from; i < len(paths); i++ {
+			if pathUsesRooms(paths[i], used) {
+				continue
+			}
+			setRooms(paths[i], used, true)
+			current = append(current, paths[i])
+			search(i + 1)
+			current = current[:len(current)-1]
+			setRooms(paths[i], used, false)
+		}
+	}
+	search(0)
+}
+
+// allSimplePaths lists every start-end path that visits no room twice
+func allSimplePaths(f *Farm, limit int) ([][]string, error) {
+	var paths [][]string
+	visited := map[string]bool{f.Start: true}
+	path := []string{f.Start}
+
+	var walk func(room string) error
+	walk = func(room string) error {
+		seen := make(map[string]bool) // duplicate tunnels give the same path
+		for _, next := range f.Rooms[room].Links {
+			if visited[next] || seen[next] {
+				continue
+			}
+			seen[next] = true
+			path = append(path, next)
+			if next == f.End {
+				if len(paths) == limit {
+					return fmt.Errorf("more than %d paths from %s to %s", limit, f.Start, f.End)
+				}
+				paths = append(paths, append([]string(nil), path...))
+			} else {
+				visited[next] = true
+				if err := walk(next); err != nil {
+					return err
+				}
+				visited[next] = false
+			}
+			path = path[:len(path)-1]
+		}
+		return nil
+	}
+	if err := walk(f.Start); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+func pathUsesRooms(path []string, used map[string]bool) bool {
+	for i := 1; i < len(path)-1; i++ {
+		if used[path[i]] {
+			return true
+		}
+	}
+	// the direct start-end tunnel can only be taken once
+	return len(path) == 2 && used[""]
+}
+
+func setRooms(path []string, used map[string]bool, value bool) {
+	for i := 1; i < len(path)-1; i++ {
+		used[path[i]] = value
+	}
+	if len(path) == 2 {
+		used[""] = value
+	}
+}
+
+// ----- verify: compare the solver with the exact minimum -----
+// lem-in verify map.txt [--max-rooms N] [--max-sets N] [--reverse-paths]
+// Exits with status 1 when the solver needs more turns than necessary.
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	maxRooms := fs.Int("max-rooms", defaultExactRooms, "refuse maps with more rooms than this")
+	maxSets := fs.Int("max-sets", 0, "also list up to N largest sets of disjoint paths, and whether the solver's is one of them")
+	fs.BoolVar(&reversePaths, "reverse-paths", false, "list the exact paths from end to start")
+	args, _ = parseFlags(fs, args)
+	if len(args) < 1 {
+		fmt.Println("Usage: go run . verify input.txt [--max-rooms N] [--max-sets N] [--reverse-paths]")
+		return
+	}
+	farm, err := parseInput(args[0], ParseOptions{})
+	if err != nil {
+		reportError(err)
+		return
+	}
+	exact, exactPaths, err := exactMinTurns(farm, *maxRooms)
+	if err != nil {
+		reportError(err)
+		return
+	}
+	paths := autoPaths(farm)
+	turns := len(simulateAnts(paths, distributeAnts(farm.Ants, paths, 0)))
+
+	fmt.Printf("Solver:        %d turns over %d paths\n", turns, len(paths))
+	fmt.Printf("Exact minimum: %d turns over %d paths\n", exact, len(exactPaths))
+	writePaths(os.Stdout, exactPaths)
+	if *maxSets > 0 {
+		sets, err := countLargestSets(farm, *maxSets, exact, paths)
+		if err != nil {
+			reportError(err)
+			return
+		}
+		atLeast := ""
+		if sets.capped {
+			atLeast = "at least "
+		}
+		fmt.Printf("Largest sets:  %s%d, of %d paths each, %d of them in %d turns\n", atLeast, sets.count, sets.size, sets.optimal, exact)
+		if sets.chosen {
+			fmt.Println("The solver's paths are one of them")
+		} else {
+			fmt.Println("The solver's paths are not one of them")
+		}
+	}
+	if turns > exact {
+		fmt.Printf("Suboptimal by %d turns\n", turns-exact)
+		os.Exit(1)
+	}
+	fmt.Println("Optimal")
+}
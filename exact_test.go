@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+// The default strategy must find the true minimum on every map small
+// enough for the exact solver
+func TestSolveQuietlyIsExact(t *testing.T) {
+	checked := 0
+	for _, name := range []string{"ex1", "ex2", "ex3", "zones", "share", "dash", "cross", "dup"} {
+		f := loadTestMap(t, name)
+		if len(f.Rooms) > defaultExactRooms {
+			continue
+		}
+		want, _, err := exactMinTurns(f, defaultExactRooms)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		_, got, err := solveQuietly(f, autoPaths)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got != want {
+			t.Errorf("%s: %d turns, the exact minimum is %d", name, got, want)
+		}
+		checked++
+	}
+	if checked == 0 {
+		t.Fatal("no map is small enough for the exact solver")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+func Example_parse() {
+	farm, err := parseInput(filepath.Join("testdata", "valid", "ex3.txt"), ParseOptions{})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(farm.Ants, "ants from", farm.Start, "to", farm.End)
+	fmt.Println("rooms:", farm.Names)
+	for _, t := range farm.Tunnels() {
+		fmt.Println("tunnel", t.A+"-"+t.B)
+	}
+	// Output:
+	// 4 ants from s to e
+	// rooms: [s a b e]
+	// tunnel a-b
+	// tunnel a-s
+	// tunnel b-e
+}
+
+func Example_solve() {
+	farm, err := parseInput(filepath.Join("testdata", "valid", "ex1.txt"), ParseOptions{})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	paths, turns, err := solveQuietly(farm, autoPaths)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, p := range paths {
+		fmt.Println(p)
+	}
+	fmt.Println(turns, "turns")
+	// Output:
+	// [start h n e end]
+	// [start t E a m end]
+	// 5 turns
+}
+
+func Example_simulate() {
+	paths := [][]string{{"s", "a", "e"}, {"s", "b", "c", "e"}}
+	for _, turn := range simulateAnts(paths, distributeAnts(4, paths, 0)) {
+		fmt.Println(turn)
+	}
+	// Output:
+	// L1-a L3-b
+	// L1-e L2-a L3-c
+	// L2-e L4-a L3-e
+	// L4-e
+}
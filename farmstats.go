@@ -0,0 +1,38 @@
+package main
+
+// ----- Derived farm figures, cached -----
+// FarmStats is computed on the first call to Stats and kept until the
+// farm is edited through addRoom or addTunnel. The cache is guarded by a
+// lock, so goroutines sharing a farm can all call Stats.
+type FarmStats struct {
+	Rooms       int
+	Tunnels     int   // distinct tunnels, see Tunnels
+	Degrees     []int // Degrees[d]: number of rooms with d tunnels
+	StartDegree int
+	EndDegree   int
+}
+
+// Stats returns the figures of the farm. The result is shared: do not
+// modify Degrees.
+func (f *Farm) Stats() FarmStats {
+	f.statsMu.Lock()
+	defer f.statsMu.Unlock()
+	// Start and End are plain fields, so also check they did not change
+	if f.stats != nil && f.statsStart == f.Start && f.statsEnd == f.End {
+		return *f.stats
+	}
+	s := FarmStats{
+		Rooms:       len(f.Names),
+		Tunnels:     len(f.Tunnels()),
+		StartDegree: f.Degree(f.Start),
+		EndDegree:   f.Degree(f.End),
+	}
+	for _, d := range f.degrees {
+		for len(s.Degrees) <= d {
+			s.Degrees = append(s.Degrees, 0)
+		}
+		s.Degrees[d]++
+	}
+	f.stats, f.statsStart, f.statsEnd = &s, f.Start, f.End
+	return s
+}
@@ -0,0 +1,21 @@
+//go:build faultinject
+
+package main
+
+// ----- Fault injection for tests (-tags faultinject) -----
+// Tests set these hooks to make the simulator see a tunnel as already used
+// or a room as taken, whatever the real state, to reach its conflict
+// handling without crafting a map for it. The ant then waits, as it would
+// in a real conflict. A nil hook injects nothing.
+var (
+	linkBusyHook     func(ant int, from, to string) bool
+	roomOccupiedHook func(ant int, room string) bool
+)
+
+func injectedLinkBusy(ant int, from, to string) bool {
+	return linkBusyHook != nil && linkBusyHook(ant, from, to)
+}
+
+func injectedRoomOccupied(ant int, room string) bool {
+	return roomOccupiedHook != nil && roomOccupiedHook(ant, room)
+}
@@ -0,0 +1,67 @@
+//go:build faultinject
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// antTurn returns the turn, from 1, on which ant enters room, or 0
+func antTurn(turns []Turn, ant int, room string) int {
+	for i, turn := range turns {
+		for _, m := range turn {
+			if m.Ant == ant && m.Room == room {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+func TestInjectedRoomOccupied(t *testing.T) {
+	defer func() { roomOccupiedHook = nil }()
+	f, paths := chainFarm([]int{3, 2}, 5)
+	dist := distributeAnts(f.Ants, paths, 0)
+	normal := simulateAnts(paths, dist)
+
+	// The second room of path 1 is taken the first time its first ant
+	// tries to enter it
+	first, b := dist[0][0], paths[0][2]
+	tried := false
+	roomOccupiedHook = func(ant int, room string) bool {
+		if ant == first && room == b && !tried {
+			tried = true
+			return true
+		}
+		return false
+	}
+	turns := simulateAnts(paths, dist)
+	if !tried {
+		t.Fatalf("the simulator never asked about room %s", b)
+	}
+	if got, want := antTurn(turns, first, b), antTurn(normal, first, b)+1; got != want {
+		t.Errorf("ant %d entered %s on turn %d, want %d", first, b, got, want)
+	}
+	// The other path does not wait
+	other := dist[1][0]
+	if got, want := antTurn(turns, other, "e"), antTurn(normal, other, "e"); got != want {
+		t.Errorf("ant %d arrived on turn %d, want %d", other, got, want)
+	}
+	sol := Solution{Farm: f, Paths: paths, Distribution: dist, Turns: turns}
+	if err := checkSolution(sol); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInjectedLinkBusy(t *testing.T) {
+	defer func() { linkBusyHook = nil }()
+	paths := [][]string{{"s", "a", "e"}}
+	linkBusyHook = func(int, string, string) bool { return true }
+	defer func() {
+		if r, _ := recover().(string); !strings.Contains(r, "stuck") {
+			t.Errorf("got %v, want the stuck simulation panic", r)
+		}
+	}()
+	simulateAnts(paths, distributeAnts(3, paths, 0))
+}
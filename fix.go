@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ----- fix: repair common map mistakes -----
+// lem-in fix map.txt > fixed.txt
+// The repaired map goes to stdout and every change made to stderr. Maps
+// that are still invalid after the repairs are refused.
+func runFix(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: go run . fix input.txt")
+		return
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		reportError(err)
+		return
+	}
+	lines, fixes, err := fixMap(strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"))
+	if err == nil {
+		err = checkFixedMap(strings.Join(lines, "\n") + "\n")
+	}
+	if err != nil {
+		reportErrorf("cannot fix %s: %w", args[0], err)
+		os.Exit(1)
+	}
+	for _, fix := range fixes {
+		fmt.Fprintln(os.Stderr, fix)
+	}
+	fmt.Print(strings.Join(lines, "\n") + "\n")
+}
+
+// fixMap strips trailing whitespace, moves a misplaced ant count to the
+// first line and drops self-loops and repeated tunnels. Line numbers in
+// the fixes are the ones of the original file.
+func fixMap(lines []string) ([]string, []string, error) {
+	var fixes []string
+	fixed := make([]string, len(lines))
+	for i, line := range lines {
+		fixed[i] = strings.TrimRight(line, " \t\r")
+		if fixed[i] != line {
+			fixes = append(fixes, fmt.Sprintf("line %d: removed trailing whitespace", i+1))
+		}
+	}
+
+	// The ant count must come first, before any room or command
+	first := -1
+	var counts []int
+	for i, line := range fixed {
+		if strings.TrimSpace(line) == "" || (strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "##")) {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		if _, err := strconv.Atoi(strings.TrimSpace(line)); err == nil {
+			counts = append(counts, i)
+		}
+	}
+	switch {
+	case first < 0:
+		return nil, nil, fmt.Errorf("the map is empty")
+	case len(counts) == 0:
+		return nil, nil, fmt.Errorf("no line holds the number of ants")
+	case len(counts) > 1:
+		numbers := make([]string, len(counts))
+		for i, n := range counts {
+			numbers[i] = strconv.Itoa(n + 1)
+		}
+		return nil, nil, fmt.Errorf("lines %s could each be the number of ants", strings.Join(numbers, ", "))
+	case counts[0] != first:
+		count := fixed[counts[0]]
+		copy(fixed[first+1:counts[0]+1], fixed[first:counts[0]])
+		fixed[first] = count
+		fixes = append(fixes, fmt.Sprintf("line %d: moved the number of ants to the top", counts[0]+1))
+	}
+
+	// Tunnels need the room names to be split
+	farm := newFarm()
+	for _, line := range fixed {
+		if fields := strings.Fields(line); len(fields) == 3 && !strings.HasPrefix(line, "#") {
+			if name, err := unquoteName(fields[0]); err == nil && farm.Rooms[name] == nil {
+				farm.addRoom(name, 0, 0)
+			}
+		}
+	}
+	origin := originalLines(len(lines), first, counts[0])
+	seen := make(map[[2]string]bool)
+	var kept []string
+	for i, line := range fixed {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") || strings.Contains(trimmed, " ") || !strings.Contains(trimmed, "-") {
+			kept = append(kept, line)
+			continue
+		}
+		a, b, err := splitTunnel(trimmed, farm, ParseOptions{})
+		if err != nil {
+			kept = append(kept, line) // reported when the result is checked
+			continue
+		}
+		if a == b {
+			fixes = append(fixes, fmt.Sprintf("line %d: removed tunnel from %s to itself", origin[i]+1, quoteName(a)))
+			continue
+		}
+		key := [2]string{a, b}
+		if b < a {
+			key = [2]string{b, a}
+		}
+		if seen[key] {
+			fixes = append(fixes, fmt.Sprintf("line %d: removed repeated tunnel %s-%s", origin[i]+1, quoteName(a), quoteName(b)))
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, line)
+	}
+	sort.SliceStable(fixes, func(i, j int) bool { return fixLine(fixes[i]) < fixLine(fixes[j]) })
+	return kept, fixes, nil
+}
+
+// originalLines maps the line indices after moving line `from` up to
+// line `to` back to the indices in the file
+func originalLines(n, to, from int) []int {
+	origin := make([]int, n)
+	for i := range origin {
+		switch {
+		case i == to:
+			origin[i] = from
+		case i > to && i <= from:
+			origin[i] = i - 1
+		default:
+			origin[i] = i
+		}
+	}
+	return origin
+}
+
+func fixLine(fix string) int {
+	var n int
+	fmt.Sscanf(fix, "line %d:", &n)
+	return n
+}
+
+// checkFixedMap parses the repaired map the way the solver would
+func checkFixedMap(text string) error {
+	file, err := os.CreateTemp("", "lem-in-fix-*.txt")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(text)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	_, err = parseInput(file.Name(), ParseOptions{})
+	return err
+}
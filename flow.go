@@ -0,0 +1,315 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ----- Flow network -----
+// Arcs are stored in pairs: arc i^1 is the reverse of arc i, with no
+// capacity and the opposite cost, so a flow is undone by pushing it back.
+type network struct {
+	arcs     []flowArc
+	arcsFrom [][]int // arcs leaving each node, by index in arcs
+}
+
+type flowArc struct {
+	to, cap, cost int
+}
+
+func newNetwork(nodes int) network {
+	return network{arcsFrom: make([][]int, nodes)}
+}
+
+func (n *network) addArc(from, to, cap, cost int) {
+	n.arcsFrom[from] = append(n.arcsFrom[from], len(n.arcs))
+	n.arcs = append(n.arcs, flowArc{to: to, cap: cap, cost: cost})
+	n.arcsFrom[to] = append(n.arcsFrom[to], len(n.arcs))
+	n.arcs = append(n.arcs, flowArc{to: from, cap: 0, cost: -cost})
+}
+
+// flowing reports whether forward arc a carries an ant
+func (n *network) flowing(a int) bool {
+	return a%2 == 0 && n.arcs[a^1].cap > 0
+}
+
+// ----- "flow" strategy: disjoint paths by maximum flow -----
+// Every room is split into an entry and an exit node joined by an arc of
+// capacity one, so that augmenting paths found by BFS on the residual
+// network never share a room. An augmenting path may go back through a
+// tunnel used by an earlier path and reroute it, which the greedy searches
+// cannot do. After each augmentation the paths are read back and the set
+// needing the fewest turns for the ants is kept: more paths only pay off
+// with enough ants.
+type roomNetwork struct {
+	network
+	farm *Farm
+}
+
+func (g *roomNetwork) in(name string) int  { return 2 * g.farm.Rooms[name].ID }
+func (g *roomNetwork) out(name string) int { return g.in(name) + 1 }
+
+// room is the name of the room that node is half of
+func (g *roomNetwork) room(node int) string { return g.farm.Names[node/2] }
+
+func newRoomNetwork(f *Farm) *roomNetwork {
+	g := &roomNetwork{network: newNetwork(2 * len(f.Names)), farm: f}
+	for _, name := range f.Names {
+		capacity := 1
+		if name == f.Start || name == f.End {
+			capacity = len(f.Rooms[name].Links)
+		}
+		g.addArc(g.in(name), g.out(name), capacity, 0)
+		seen := make(map[string]bool) // repeated tunnels are one tunnel
+		for _, next := range f.Rooms[name].Links {
+			if seen[next] || next == f.Start || name == f.End {
+				continue
+			}
+			seen[next] = true
+			g.addArc(g.out(name), g.in(next), 1, 0)
+		}
+	}
+	return g
+}
+
+// augment pushes one ant along a shortest residual path and returns the
+// arcs it used, or nil when the flow is already maximal.
+func (g *roomNetwork) augment() []int {
+	source, sink := g.out(g.farm.Start), g.in(g.farm.End)
+	via := make([]int, len(g.arcsFrom))
+	for i := range via {
+		via[i] = -1
+	}
+	queue := []int{source}
+	for len(queue) > 0 && via[sink] < 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, a := range g.arcsFrom[node] {
+			if to := g.arcs[a].to; g.arcs[a].cap > 0 && via[to] < 0 && to != source {
+				via[to] = a
+				queue = append(queue, to)
+			}
+		}
+	}
+	if via[sink] < 0 {
+		return nil
+	}
+	var used []int
+	for node := sink; node != source; node = g.arcs[via[node]^1].to {
+		used = append(used, via[node])
+	}
+	for _, a := range used {
+		g.arcs[a].cap--
+		g.arcs[a^1].cap++
+	}
+	return used
+}
+
+// paths reads the current flow back as paths, on a copy of the network.
+// Ants going round in a loop are cut out of the path they belong to.
+func (g *roomNetwork) paths() [][]string {
+	n := network{arcs: append([]flowArc(nil), g.arcs...), arcsFrom: g.arcsFrom}
+	start, end := g.farm.Start, g.farm.End
+	var paths [][]string
+	for {
+		node := g.out(start)
+		path := []string{start}
+		at := map[string]int{start: 0}
+		for node != g.in(end) {
+			next := -1
+			for _, a := range n.arcsFrom[node] {
+				if n.flowing(a) {
+					next = a
+					break
+				}
+			}
+			if next < 0 {
+				return paths
+			}
+			n.arcs[next^1].cap--
+			node = n.arcs[next].to
+			if node%2 == 1 {
+				continue // the exit of the room just entered
+			}
+			room := g.room(node)
+			if i, seen := at[room]; seen {
+				for _, r := range path[i+1:] {
+					delete(at, r)
+				}
+				path = path[:i+1]
+				continue
+			}
+			at[room] = len(path)
+			path = append(path, room)
+		}
+		paths = append(paths, path)
+	}
+}
+
+func flowPaths(f *Farm) [][]string {
+	g := newRoomNetwork(f)
+	var best [][]string
+	bestTurns := -1
+	for step := 1; ; step++ {
+		used := g.augment()
+		if used == nil {
+			break
+		}
+		paths := g.paths()
+		sort.SliceStable(paths, func(i, j int) bool { return pathLess(paths[i], paths[j]) })
+		turns := predictTurns(paths, f.Ants, maxAntsPerPath)
+		if flowTrace != nil {
+			flowTrace.frame(g, step, used, len(paths), turns)
+		}
+		if bestTurns < 0 || turns < bestTurns {
+			best, bestTurns = paths, turns
+		}
+	}
+	return best
+}
+
+// ----- Minimum cut between start and end -----
+// Once the flow is maximal, the nodes still reachable from the start in
+// the residual network are cut off from the end by saturated arcs. Each
+// one is a room (its entry-exit arc) or a tunnel, and there are as many
+// as there are disjoint paths: they are why there are no more.
+type farmCut struct {
+	rooms   map[string]bool
+	tunnels map[[2]string]bool // as the ants cross them, away from start
+}
+
+func minCut(f *Farm) farmCut {
+	g := newRoomNetwork(f)
+	for g.augment() != nil {
+	}
+	reached := make([]bool, len(g.arcsFrom))
+	source := g.out(f.Start)
+	reached[source] = true
+	queue := []int{source}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, a := range g.arcsFrom[node] {
+			if to := g.arcs[a].to; g.arcs[a].cap > 0 && !reached[to] {
+				reached[to] = true
+				queue = append(queue, to)
+			}
+		}
+	}
+	cut := farmCut{rooms: make(map[string]bool), tunnels: make(map[[2]string]bool)}
+	for a := 0; a < len(g.arcs); a += 2 {
+		from, to := g.arcs[a^1].to, g.arcs[a].to
+		if !reached[from] || reached[to] {
+			continue
+		}
+		if g.room(from) == g.room(to) {
+			cut.rooms[g.room(from)] = true
+		} else {
+			cut.tunnels[[2]string{g.room(from), g.room(to)}] = true
+		}
+	}
+	return cut
+}
+
+func (c farmCut) size() int { return len(c.rooms) + len(c.tunnels) }
+
+// ----- Trace the flow search as DOT frames (--trace-algo dir) -----
+// One frame per augmentation: tunnels carrying an ant are drawn in blue
+// with the direction of the ant, the augmenting path just found in red.
+// Where the red arrow goes backwards along a tunnel of an earlier path,
+// that path has just been rerouted.
+type flowTracer struct {
+	dir string
+	err error // first write error, frames after it are skipped
+}
+
+// flowTrace is set once from the command line before any search runs
+var flowTrace *flowTracer
+
+func (t *flowTracer) frame(g *roomNetwork, step int, used []int, paths, turns int) {
+	if t.err != nil {
+		return
+	}
+	name := filepath.Join(t.dir, fmt.Sprintf("frame_%03d.dot", step))
+	t.err = writeFile(name, g.farm, func(w io.Writer, f *Farm) error {
+		return writeFlowFrame(w, g, step, used, paths, turns)
+	})
+}
+
+func writeFlowFrame(w io.Writer, g *roomNetwork, step int, used []int, paths, turns int) error {
+	f := g.farm
+	augmenting := make(map[[2]string]bool)
+	for _, a := range used {
+		from, to := g.room(g.arcs[a^1].to), g.room(g.arcs[a].to)
+		if from != to {
+			augmenting[[2]string{from, to}] = true
+		}
+	}
+	flows := make(map[[2]string]bool)
+	for a := range g.arcs {
+		if g.flowing(a) {
+			if from, to := g.room(g.arcs[a^1].to), g.room(g.arcs[a].to); from != to {
+				flows[[2]string{from, to}] = true
+			}
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "digraph flow {\n\tlabel=%q;\n", fmt.Sprintf("augmentation %d: %d paths, %d turns", step, paths, turns)); err != nil {
+		return err
+	}
+	for _, name := range f.Names {
+		attrs := ""
+		switch name {
+		case f.Start:
+			attrs = " [shape=doublecircle]"
+		case f.End:
+			attrs = " [shape=doublecircle, style=filled]"
+		}
+		if _, err := fmt.Fprintf(w, "\t%q%s;\n", name, attrs); err != nil {
+			return err
+		}
+	}
+	drawn := make(map[[2]string]bool)
+	for _, name := range f.Names {
+		for _, next := range f.Rooms[name].Links {
+			tunnel := [2]string{name, next}
+			if name > next {
+				tunnel = [2]string{next, name}
+			}
+			if drawn[tunnel] {
+				continue
+			}
+			drawn[tunnel] = true
+			var err error
+			a, b := tunnel[0], tunnel[1]
+			switch {
+			case augmenting[[2]string{a, b}]:
+				_, err = fmt.Fprintf(w, "\t%q -> %q [color=red, penwidth=2];\n", a, b)
+			case augmenting[[2]string{b, a}]:
+				_, err = fmt.Fprintf(w, "\t%q -> %q [color=red, penwidth=2];\n", b, a)
+			case flows[[2]string{a, b}]:
+				_, err = fmt.Fprintf(w, "\t%q -> %q [color=blue];\n", a, b)
+			case flows[[2]string{b, a}]:
+				_, err = fmt.Fprintf(w, "\t%q -> %q [color=blue];\n", b, a)
+			default:
+				_, err = fmt.Fprintf(w, "\t%q -> %q [dir=none, color=gray];\n", a, b)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+func startFlowTrace(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	flowTrace = &flowTracer{dir: dir}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ----- Canonical text forms -----
+
+// String returns the farm in the input format: ant count, rooms in input
+// order (with their ##start, ##end, ##zone and ##spawn commands) and then
+// every tunnel once.
+func (f *Farm) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d\n", f.Ants)
+	for _, name := range f.Names {
+		room := f.Rooms[name]
+		switch name {
+		case f.Start:
+			sb.WriteString("##start\n")
+		case f.End:
+			sb.WriteString("##end\n")
+		}
+		fmt.Fprintf(&sb, "%s %d %d\n", quoteName(name), room.X, room.Y)
+	}
+	for _, zone := range f.Zones {
+		sb.WriteString("##zone " + zone.Name)
+		for _, name := range zone.Rooms {
+			sb.WriteString(" " + name)
+		}
+		sb.WriteByte('\n')
+	}
+	for _, spawn := range f.Spawns {
+		fmt.Fprintf(&sb, "##spawn %d ants at turn %d\n", spawn.Ants, spawn.Turn)
+	}
+	for _, tunnel := range f.tunnelList() {
+		fmt.Fprintf(&sb, "%s-%s\n", quoteName(tunnel[0]), quoteName(tunnel[1]))
+	}
+	return sb.String()
+}
+
+// tunnelList rebuilds the tunnels from the Links, each one once, ordered
+// by the input order of their rooms, for the exports that write a map
+// back out; Tunnels is the canonical list
+func (f *Farm) tunnelList() [][2]string {
+	var tunnels [][2]string
+	seen := make(map[[2]string]bool)
+	for _, name := range f.Names {
+		for _, link := range f.Rooms[name].Links {
+			tunnel := [2]string{name, link}
+			if f.Rooms[link].ID < f.Rooms[name].ID {
+				tunnel = [2]string{link, name}
+			}
+			if !seen[tunnel] {
+				seen[tunnel] = true
+				tunnels = append(tunnels, tunnel)
+			}
+		}
+	}
+	return tunnels
+}
+
+// String returns the moves of the turn on one line, without the newline
+func (t Turn) String() string {
+	var sb strings.Builder
+	writeTurn(&sb, t)
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// String returns one line of moves per turn
+func (s Solution) String() string {
+	var sb strings.Builder
+	for _, turn := range s.Turns {
+		writeTurn(&sb, turn)
+	}
+	return sb.String()
+}
+
+// ----- Output in the subject's format (--strict) -----
+// The map, an empty line, then one line per turn with the moves
+// separated by single spaces and no trailing space. A turn without
+// moves is an error rather than an empty line.
+var errEmptyTurn = errors.New("turn with no moves")
+
+func writeSpecHeader(w io.Writer, f *Farm) error {
+	_, err := io.WriteString(w, f.String()+"\n")
+	return err
+}
+
+// specTurns wraps a turn writer to refuse empty turns
+func specTurns(write turnWriter) turnWriter {
+	return func(w io.Writer, turn Turn) error {
+		if len(turn) == 0 {
+			return errEmptyTurn
+		}
+		return write(w, turn)
+	}
+}
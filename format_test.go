@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// One line of the subject's output: moves separated by single spaces
+var specLine = regexp.MustCompile(`^L[1-9][0-9]*-[^ ]+( L[1-9][0-9]*-[^ ]+)*$`)
+
+func TestWriteTurn(t *testing.T) {
+	tests := []struct {
+		turn Turn
+		want string
+	}{
+		{Turn{{Ant: 1, Room: "a"}}, "L1-a\n"},
+		{Turn{{Ant: 1, Room: "b"}, {Ant: 2, Room: "a"}}, "L1-b L2-a\n"},
+		{Turn{{Ant: 10, Room: "end"}, {Ant: 11, Room: "room-a"}, {Ant: 12, Room: "x"}}, "L10-end L11-room-a L12-x\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeTurn(&buf, tt.turn); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("writeTurn(%v) = %q, want %q", tt.turn, buf.String(), tt.want)
+		}
+	}
+}
+
+// encodeStrict writes a solution the way --strict does
+func encodeStrict(t *testing.T, s Solution, moves turnWriter) string {
+	t.Helper()
+	enc, err := lookupEncoder("text", EncoderOptions{Moves: moves, Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := enc.Begin(&buf, s.Farm); err != nil {
+		t.Fatal(err)
+	}
+	for _, turn := range s.Turns {
+		if err := enc.Turn(&buf, turn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.End(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestStrictOutput(t *testing.T) {
+	for _, name := range []string{"ex1", "ex2", "ex3", "zones", "dash"} {
+		s := solveMap(t, name)
+		for _, moves := range []turnWriter{writeTurn, groupedByPath(false)} {
+			out := encodeStrict(t, s, moves)
+			header, rest, ok := strings.Cut(out, "\n\n")
+			if !ok || header+"\n" != s.Farm.String() {
+				t.Fatalf("%s: the output does not start with the map and a blank line:\n%s", name, out)
+			}
+			if !strings.HasSuffix(rest, "\n") || strings.HasSuffix(rest, "\n\n") {
+				t.Errorf("%s: the moves do not end with exactly one newline", name)
+			}
+			lines := strings.Split(strings.TrimSuffix(rest, "\n"), "\n")
+			if len(lines) != len(s.Turns) {
+				t.Errorf("%s: %d lines for %d turns", name, len(lines), len(s.Turns))
+			}
+			for _, line := range lines {
+				if !specLine.MatchString(line) {
+					t.Errorf("%s: line %q is not in the subject's format", name, line)
+				}
+			}
+		}
+	}
+}
+
+func TestStrictEmptyTurn(t *testing.T) {
+	enc, err := lookupEncoder("text", EncoderOptions{Moves: writeTurn, Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := enc.Turn(&buf, Turn{}); !errors.Is(err, errEmptyTurn) {
+		t.Errorf("empty turn: got %v, want %v", err, errEmptyTurn)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("empty turn wrote %q", buf.String())
+	}
+
+	// Without --strict an empty turn is an empty line
+	enc, _ = lookupEncoder("text", EncoderOptions{Moves: writeTurn})
+	if err := enc.Turn(&buf, Turn{}); err != nil || buf.String() != "\n" {
+		t.Errorf("empty turn without --strict: %q, %v", buf.String(), err)
+	}
+}
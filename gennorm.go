@@ -0,0 +1,185 @@
+//go:build ignore
+
+// gennorm writes normtables.go from the Unicode character database:
+//
+//	go run gennorm.go UnicodeData.txt CompositionExclusions.txt
+//
+// Both files are in https://www.unicode.org/Public/<version>/ucd/; use the
+// version of the unicode package of the Go release that builds lem-in.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		log.Fatal("usage: go run gennorm.go UnicodeData.txt CompositionExclusions.txt")
+	}
+	classes := make(map[rune]uint8)
+	decompositions := make(map[rune][]rune)
+	err := eachLine(os.Args[1], func(fields []string) error {
+		r, err := parseRune(fields[0])
+		if err != nil || len(fields) < 6 {
+			return fmt.Errorf("invalid line %q", strings.Join(fields, ";"))
+		}
+		class, err := strconv.ParseUint(fields[3], 10, 8)
+		if err != nil {
+			return err
+		}
+		if class != 0 {
+			classes[r] = uint8(class)
+		}
+		// Compatibility decompositions start with a <tag>
+		if fields[5] == "" || strings.HasPrefix(fields[5], "<") {
+			return nil
+		}
+		for _, code := range strings.Fields(fields[5]) {
+			c, err := parseRune(code)
+			if err != nil {
+				return err
+			}
+			decompositions[r] = append(decompositions[r], c)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Only two-rune decompositions of starters into starter and mark can
+	// compose; an exclusion matters for no other rune
+	var exclusions []rune
+	err = eachLine(os.Args[2], func(fields []string) error {
+		r, err := parseRune(fields[0])
+		if err != nil {
+			return err
+		}
+		if d := decompositions[r]; len(d) == 2 && classes[r] == 0 && classes[d[0]] == 0 {
+			exclusions = append(exclusions, r)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintln(&out, "// Code generated by gennorm.go; DO NOT EDIT.")
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "package main")
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "// Runes with a non-zero canonical combining class, in ranges of one class")
+	fmt.Fprintln(&out, "var combiningClasses = []combiningRange{")
+	for i, r := range classRanges(classes) {
+		fmt.Fprintf(&out, "{0x%04X, 0x%04X, %d},", r.lo, r.hi, r.class)
+		endRow(&out, i, 4)
+	}
+	fmt.Fprintln(&out, "\n}")
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "// Canonical decompositions, one level deep")
+	fmt.Fprintln(&out, "var canonicalDecompositions = map[rune][]rune{")
+	for i, r := range sortedRunes(decompositions) {
+		fmt.Fprintf(&out, "0x%04X: {", r)
+		for j, c := range decompositions[r] {
+			if j > 0 {
+				fmt.Fprint(&out, ", ")
+			}
+			fmt.Fprintf(&out, "0x%04X", c)
+		}
+		fmt.Fprint(&out, "},")
+		endRow(&out, i, 4)
+	}
+	fmt.Fprintln(&out, "\n}")
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "// Runes that are never composed back from their decomposition")
+	fmt.Fprintln(&out, "var compositionExclusions = []rune{")
+	sort.Slice(exclusions, func(i, j int) bool { return exclusions[i] < exclusions[j] })
+	for i, r := range exclusions {
+		fmt.Fprintf(&out, "0x%04X,", r)
+		endRow(&out, i, 8)
+	}
+	fmt.Fprintln(&out, "\n}")
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("normtables.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// eachLine calls fn with the ';'-separated fields of every line that is
+// not a comment
+func eachLine(filename string, fn func(fields []string) error) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, ";")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		if err := fn(fields); err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func parseRune(code string) (rune, error) {
+	r, err := strconv.ParseUint(code, 16, 32)
+	return rune(r), err
+}
+
+type classRange struct {
+	lo, hi rune
+	class  uint8
+}
+
+func classRanges(classes map[rune]uint8) []classRange {
+	var ranges []classRange
+	for _, r := range sortedRunes(classes) {
+		n := len(ranges)
+		if n > 0 && ranges[n-1].hi == r-1 && ranges[n-1].class == classes[r] {
+			ranges[n-1].hi = r
+			continue
+		}
+		ranges = append(ranges, classRange{r, r, classes[r]})
+	}
+	return ranges
+}
+
+func sortedRunes[V any](m map[rune]V) []rune {
+	runes := make([]rune, 0, len(m))
+	for r := range m {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	return runes
+}
+
+// endRow starts a new line after every perRow entries
+func endRow(out *bytes.Buffer, i, perRow int) {
+	if i%perRow == perRow-1 {
+		fmt.Fprintln(out)
+	} else {
+		fmt.Fprint(out, " ")
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"container/heap"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ----- Order of the start neighbours (--heuristic) -----
+// findNonOverlappingPaths grows one path per start neighbour, each one
+// blocking the rooms of the next ones, so the order matters. A heuristic
+// scores every room given a farm; lower scores are tried first and ties
+// are broken by name.
+type heuristic func(f *Farm) func(room string) int
+
+var heuristics = map[string]heuristic{
+	// Fewest tunnels first: busy rooms are left to the later paths
+	"degree": func(f *Farm) func(string) int { return f.Degree },
+	// Closest to the end first, by number of tunnels
+	"distance": func(f *Farm) func(string) int {
+		dist := distancesToEnd(f)
+		return func(room string) int {
+			if d, ok := dist[room]; ok {
+				return d
+			}
+			return math.MaxInt
+		}
+	},
+	// Widest way to the end first: the room with the fewest tunnels on the
+	// best way there bounds how many paths can branch off it
+	"bottleneck": func(f *Farm) func(string) int {
+		width := widthsToEnd(f)
+		return func(room string) int { return -width[room] }
+	},
+}
+
+// neighborHeuristic is set once from the command line before any search runs
+var neighborHeuristic = "degree"
+
+func heuristicNames() []string {
+	names := make([]string, 0, len(heuristics))
+	for name := range heuristics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// distancesToEnd is a BFS from the end room that never goes through start
+func distancesToEnd(f *Farm) map[string]int {
+	dist := map[string]int{f.End: 0}
+	queue := []string{f.End}
+	for len(queue) > 0 {
+		room := queue[0]
+		queue = queue[1:]
+		for _, next := range f.Rooms[room].Links {
+			if _, seen := dist[next]; !seen && next != f.Start {
+				dist[next] = dist[room] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return dist
+}
+
+// widthsToEnd gives every room the largest, over the ways from it to the
+// end, of the smallest degree met on the way (the room itself included,
+// the end room not). Rooms that cannot reach the end have width 0.
+func widthsToEnd(f *Farm) map[string]int {
+	width := map[string]int{f.End: math.MaxInt}
+	done := make(map[string]bool)
+	queue := &widthQueue{{room: f.End, width: math.MaxInt}}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(widthItem)
+		if done[item.room] {
+			continue
+		}
+		done[item.room] = true
+		for _, next := range f.Rooms[item.room].Links {
+			if next == f.Start || done[next] {
+				continue
+			}
+			w := item.width
+			if d := f.Degree(next); d < w {
+				w = d
+			}
+			if w > width[next] {
+				width[next] = w
+				heap.Push(queue, widthItem{room: next, width: w})
+			}
+		}
+	}
+	return width
+}
+
+type widthItem struct {
+	room  string
+	width int
+}
+
+// widthQueue pops the widest room first
+type widthQueue []widthItem
+
+func (q widthQueue) Len() int            { return len(q) }
+func (q widthQueue) Less(i, j int) bool  { return q[i].width > q[j].width }
+func (q widthQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *widthQueue) Push(x interface{}) { *q = append(*q, x.(widthItem)) }
+func (q *widthQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// ----- bench: compare the heuristics on a set of maps -----
+// lem-in bench [--heuristics a,b,...] [--algo name] map1.txt map2.txt ...
+// Prints the turns each heuristic needs on every map, then for each
+// heuristic the total and the number of maps where it is the best (ties
+// count for all of them).
+func runBench(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	names := fs.String("heuristics", strings.Join(heuristicNames(), ","), "heuristics to compare")
+	algo := fs.String("algo", envDefault("LEMIN_ALGO", "auto"), "path-finding strategy (nonoverlap shows the heuristics alone)")
+	files, _ := parseFlags(fs, args)
+	if len(files) == 0 {
+		fmt.Println("Usage: go run . bench [--heuristics a,b,...] [--algo name] map1.txt map2.txt ...")
+		return
+	}
+	strategy, err := lookupStrategy(*algo)
+	if err != nil {
+		reportError(err)
+		return
+	}
+	compared := strings.Split(*names, ",")
+	for _, name := range compared {
+		if heuristics[name] == nil {
+			reportErrorf("unknown heuristic %q", name)
+			return
+		}
+	}
+
+	fmt.Printf("%-30s", "map")
+	for _, name := range compared {
+		fmt.Printf(" %12s", name)
+	}
+	fmt.Println()
+	totals := make([]int, len(compared))
+	wins := make([]int, len(compared))
+	failed := 0
+	live := newLiveTable(len(files))
+	for _, file := range files {
+		live.started(file)
+		farm, err := parseInput(file, ParseOptions{})
+		if err != nil {
+			live.finished(file, 0, false)
+			live.hide()
+			fmt.Printf("%-30s error: %v\n", file, err)
+			live.show()
+			failed++
+			continue
+		}
+		turns := make([]int, len(compared))
+		best := -1
+		for i, name := range compared {
+			neighborHeuristic = name
+			_, turns[i], err = solveQuietly(farm, strategy)
+			if err != nil {
+				break
+			}
+			if best < 0 || turns[i] < best {
+				best = turns[i]
+			}
+		}
+		live.finished(file, best, err == nil)
+		live.hide()
+		if err != nil {
+			fmt.Printf("%-30s error: %v\n", file, err)
+			live.show()
+			failed++
+			continue
+		}
+		fmt.Printf("%-30s", file)
+		for i, t := range turns {
+			fmt.Printf(" %12d", t)
+			totals[i] += t
+			if t == best {
+				wins[i]++
+			}
+		}
+		fmt.Println()
+		live.show()
+	}
+	live.close()
+	fmt.Printf("%-30s", "total")
+	for _, t := range totals {
+		fmt.Printf(" %12d", t)
+	}
+	fmt.Printf("\n%-30s", "best on")
+	for _, w := range wins {
+		fmt.Printf(" %12d", w)
+	}
+	fmt.Println()
+	if failed > 0 {
+		fmt.Printf("%d of %d maps failed\n", failed, len(files))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ----- JSON form of a solution (--format json) -----
+// The schema version only goes up when a field changes meaning or goes
+// away; new fields are added without a bump and older decoders ignore them.
+const solutionSchemaVersion = 1
+
+type solutionJSON struct {
+	SchemaVersion int        `json:"schemaVersion"`
+	Ants          int        `json:"ants"`
+	Start         string     `json:"start"`
+	End           string     `json:"end"`
+	Paths         [][]string `json:"paths"`
+	Distribution  [][]int    `json:"distribution"`
+	Turns         []Turn     `json:"turns"`
+}
+
+func writeSolutionJSON(w io.Writer, s Solution) error {
+	doc := solutionJSON{
+		SchemaVersion: solutionSchemaVersion,
+		Ants:          s.Farm.Ants,
+		Start:         s.Farm.Start,
+		End:           s.Farm.End,
+		Paths:         s.Paths,
+		Distribution:  s.Distribution,
+		Turns:         s.Turns,
+	}
+	if doc.Turns == nil {
+		doc.Turns = []Turn{}
+	}
+	return json.NewEncoder(w).Encode(doc)
+}
+
+// ParseSolutionJSON decodes a document written with --format json. The
+// map itself is not part of it: set Farm before checking the solution.
+func ParseSolutionJSON(data []byte) (Solution, error) {
+	var doc solutionJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return Solution{}, fmt.Errorf("invalid solution JSON: %v", err)
+	}
+	switch {
+	case doc.SchemaVersion == 0:
+		return Solution{}, fmt.Errorf("invalid solution JSON: no schemaVersion")
+	case doc.SchemaVersion > solutionSchemaVersion:
+		return Solution{}, fmt.Errorf("solution JSON has schema version %d, only %d is supported", doc.SchemaVersion, solutionSchemaVersion)
+	}
+	return Solution{Paths: doc.Paths, Distribution: doc.Distribution, Turns: doc.Turns}, nil
+}
+
+// ----- JSON Lines form of the moves (--format jsonl) -----
+// One object per turn, written as soon as the turn is computed, so that a
+// consumer never has to hold the whole solution.
+type turnJSON struct {
+	Turn  int  `json:"turn"`
+	Moves Turn `json:"moves"`
+}
+
+// jsonLines numbers the turns it writes from 1
+func jsonLines() turnWriter {
+	n := 0
+	return func(w io.Writer, turn Turn) error {
+		n++
+		if turn == nil {
+			turn = Turn{}
+		}
+		return json.NewEncoder(w).Encode(turnJSON{Turn: n, Moves: turn})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// solveMap solves a map of testdata/valid the way the default run does
+func solveMap(t *testing.T, name string) Solution {
+	t.Helper()
+	f := loadTestMap(t, name)
+	paths := autoPaths(f)
+	d := distributeAnts(f.Ants, paths, 0)
+	return Solution{Farm: f, Paths: paths, Distribution: d, Turns: simulateAnts(paths, d)}
+}
+
+func TestSolutionJSONRoundTrip(t *testing.T) {
+	for _, name := range []string{"ex1", "ex2", "dash", "zones"} {
+		s := solveMap(t, name)
+		var buf bytes.Buffer
+		if err := writeSolutionJSON(&buf, s); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), `"schemaVersion":1`) {
+			t.Errorf("%s: no schema version in %s", name, buf.String())
+		}
+		got, err := ParseSolutionJSON(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got.Farm = s.Farm
+		if !EquivalentSolutions(s, got) || got.String() != s.String() {
+			t.Errorf("%s: the solution read back differs", name)
+		}
+		if err := checkSolution(got); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestParseSolutionJSONVersions(t *testing.T) {
+	if _, err := ParseSolutionJSON([]byte(`{"schemaVersion":2}`)); err == nil {
+		t.Error("a future schema version was accepted")
+	}
+	if _, err := ParseSolutionJSON([]byte(`{"turns":[]}`)); err == nil {
+		t.Error("a solution without schema version was accepted")
+	}
+	s, err := ParseSolutionJSON([]byte(`{"schemaVersion":1,"extra":3,"turns":[[{"ant":1,"room":"x"}]]}`))
+	if err != nil || s.Turns[0][0].Room != "x" {
+		t.Errorf("unknown fields should be ignored: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ----- Parser limits -----
+// Bounds on what a map may hold, so untrusted maps cannot use unbounded
+// memory. A zero field means the default.
+type Limits struct {
+	MaxRooms      int
+	MaxTunnels    int
+	MaxLineLength int
+	MaxFileSize   int64
+}
+
+var defaultLimits = Limits{
+	MaxRooms:      1000000,
+	MaxTunnels:    5000000,
+	MaxLineLength: 4096,
+	MaxFileSize:   256 << 20,
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxRooms == 0 {
+		l.MaxRooms = defaultLimits.MaxRooms
+	}
+	if l.MaxTunnels == 0 {
+		l.MaxTunnels = defaultLimits.MaxTunnels
+	}
+	if l.MaxLineLength == 0 {
+		l.MaxLineLength = defaultLimits.MaxLineLength
+	}
+	if l.MaxFileSize == 0 {
+		l.MaxFileSize = defaultLimits.MaxFileSize
+	}
+	return l
+}
+
+// parseLimits reads a --limits value such as "rooms=5000,size=1048576";
+// the keys are rooms, tunnels, line and size (in bytes)
+func parseLimits(s string) (Limits, error) {
+	var l Limits
+	if s == "" {
+		return l, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(item, "=")
+		n, err := strconv.ParseInt(value, 10, 64)
+		if !ok || err != nil || n <= 0 {
+			return l, fmt.Errorf("invalid limit %q: want key=N with N > 0", item)
+		}
+		switch key {
+		case "rooms":
+			l.MaxRooms = int(n)
+		case "tunnels":
+			l.MaxTunnels = int(n)
+		case "line":
+			l.MaxLineLength = int(n)
+		case "size":
+			l.MaxFileSize = n
+		default:
+			return l, fmt.Errorf("unknown limit %q: use rooms, tunnels, line or size", key)
+		}
+	}
+	return l, nil
+}
+
+func checkFileSize(file *os.File, l Limits) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Mode().IsRegular() && info.Size() > l.MaxFileSize {
+		return fmt.Errorf("%s is %d bytes, more than the limit of %d", file.Name(), info.Size(), l.MaxFileSize)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ----- Live progress table (batch and bench on a terminal) -----
+// While maps are solved, a few lines at the bottom of stderr show how
+// many are done, which ones are being solved, the time left and the best
+// and worst turn counts so far. The table is redrawn a few times per
+// second; result lines are printed above it by hiding it first. When
+// stderr is not a terminal there is no table, and the result lines are
+// the only log.
+const liveRefresh = 200 * time.Millisecond
+
+// Longest list of maps being solved, in bytes
+const liveLineWidth = 72
+
+type liveTable struct {
+	mu      sync.Mutex
+	out     io.Writer
+	total   int
+	done    int
+	failed  int
+	solving []string
+	best    int // -1 until a map is solved
+	worst   int
+	start   time.Time
+	drawn   int // lines of the table on screen
+	hidden  bool
+	stop    chan struct{}
+}
+
+// newLiveTable starts the table for total maps, or returns nil when
+// stderr is not a terminal. Every method does nothing on nil.
+func newLiveTable(total int) *liveTable {
+	if !isTerminal(os.Stderr) || os.Getenv("TERM") == "dumb" {
+		return nil
+	}
+	t := &liveTable{out: os.Stderr, total: total, best: -1, start: time.Now(), stop: make(chan struct{})}
+	go func() {
+		tick := time.NewTicker(liveRefresh)
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+				t.mu.Lock()
+				if !t.hidden {
+					t.draw()
+				}
+				t.mu.Unlock()
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+	return t
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// started adds a map to the ones being solved
+func (t *liveTable) started(file string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.solving = append(t.solving, file)
+}
+
+// finished counts a map as done, with its turns when ok
+func (t *liveTable) finished(file string, turns int, ok bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, f := range t.solving {
+		if f == file {
+			t.solving = append(t.solving[:i], t.solving[i+1:]...)
+			break
+		}
+	}
+	t.done++
+	switch {
+	case !ok:
+		t.failed++
+	case t.best < 0:
+		t.best, t.worst = turns, turns
+	case turns < t.best:
+		t.best = turns
+	case turns > t.worst:
+		t.worst = turns
+	}
+}
+
+// hide clears the table so that result lines can be printed; show puts
+// it back below them
+func (t *liveTable) hide() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.clear()
+	t.hidden = true
+}
+
+func (t *liveTable) show() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hidden = false
+	t.draw()
+}
+
+// close stops the redrawing and leaves the screen as if there had been
+// no table
+func (t *liveTable) close() {
+	if t == nil {
+		return
+	}
+	close(t.stop)
+	t.hide()
+}
+
+// clear moves back to the first line of the table and erases it all
+func (t *liveTable) clear() {
+	if t.drawn > 0 {
+		fmt.Fprintf(t.out, "\x1b[%dF\x1b[J", t.drawn)
+		t.drawn = 0
+	}
+}
+
+func (t *liveTable) draw() {
+	elapsed := time.Since(t.start)
+	eta := "unknown"
+	if t.done > 0 {
+		left := elapsed / time.Duration(t.done) * time.Duration(t.total-t.done)
+		eta = left.Round(time.Second).String()
+	}
+	failed := ""
+	if t.failed > 0 {
+		failed = fmt.Sprintf(" (%d failed)", t.failed)
+	}
+	solving := strings.Join(t.solving, ", ")
+	if solving == "" {
+		solving = "-"
+	}
+	if len(solving) > liveLineWidth {
+		solving = solving[:liveLineWidth-3] + "..."
+	}
+	turns := "none yet"
+	if t.best >= 0 {
+		turns = fmt.Sprintf("best %d, worst %d", t.best, t.worst)
+	}
+	lines := []string{
+		fmt.Sprintf("Maps done:   %d/%d%s", t.done, t.total, failed),
+		fmt.Sprintf("Solving:     %s", solving),
+		fmt.Sprintf("Elapsed:     %s, ETA %s", elapsed.Round(time.Second), eta),
+		fmt.Sprintf("Turns:       %s", turns),
+	}
+	t.clear()
+	for _, line := range lines {
+		fmt.Fprintf(t.out, "%s\x1b[K\n", line)
+	}
+	t.drawn = len(lines)
+}
@@ -2,57 +2,198 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math/rand"
 	"os"
+	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 )
 
 // Room structure
 type Room struct {
+	ID    int // index of the name in Farm.Names
 	Name  string
 	X, Y  int
 	Links []string
+	Zone  string
 }
 
-// Farm structure
+// Zone structure: a named group of rooms (##zone name room1 room2 ...)
+type Zone struct {
+	Name  string
+	Rooms []string
+}
+
+// Farm structure. Once parsed it is only read (see Clone), and it is safe
+// to share between goroutines.
 type Farm struct {
-	Ants  int
-	Rooms map[string]*Room
-	Start string
-	End   string
+	Ants   int
+	Rooms  map[string]*Room
+	Names  []string // interned room names, in input order
+	Start  string
+	End    string
+	Zones  []Zone
+	Spawns []Spawn // ants available later (##spawn), not counted in Ants
+
+	degrees []int // tunnels of each room, by ID, kept up by addTunnel
+
+	tunnelsMu     sync.Mutex
+	tunnels       []Tunnel // see Tunnels, appended to by addTunnel
+	tunnelsSorted bool
+
+	statsMu              sync.Mutex
+	stats                *FarmStats // see Stats, reset by addRoom and addTunnel
+	statsStart, statsEnd string
+}
+
+func newFarm() *Farm {
+	return &Farm{Rooms: make(map[string]*Room)}
+}
+
+// addRoom interns the room name: the copy stored here is the only one kept,
+// every tunnel and path refers back to it.
+func (f *Farm) addRoom(name string, x, y int) *Room {
+	name = strings.Clone(name)
+	room := &Room{ID: len(f.Names), Name: name, X: x, Y: y}
+	f.Rooms[name] = room
+	f.Names = append(f.Names, name)
+	f.degrees = append(f.degrees, 0)
+	f.stats = nil
+	return room
+}
+
+// addTunnel links two rooms using their interned names, so the tunnel
+// lines read from the input are not kept alive by the Links slices.
+func (f *Farm) addTunnel(a, b *Room) {
+	a.Links = append(a.Links, b.Name)
+	b.Links = append(b.Links, a.Name)
+	f.degrees[a.ID]++
+	f.degrees[b.ID]++
+	f.tunnels = append(f.tunnels, newTunnel(a.Name, b.Name))
+	f.tunnelsSorted = false
+	f.stats = nil
+}
+
+// Degree returns the number of tunnels of a room (0 for an unknown room)
+func (f *Farm) Degree(name string) int {
+	if room := f.Rooms[name]; room != nil {
+		return f.degrees[room.ID]
+	}
+	return 0
+}
+
+// Move structure: one ant entering one room
+type Move struct {
+	Ant  int    `json:"ant"`
+	Room string `json:"room"`
+	Path int    `json:"-"` // index of the ant's path, set by the simulator
+}
+
+// Turn structure: every move made during a single turn
+type Turn []Move
+
+// Solution structure: the paths used, the ants sent on each path and the
+// resulting turns
+type Solution struct {
+	Farm         *Farm
+	Paths        [][]string
+	Distribution [][]int
+	Turns        []Turn
+
+	routes []antRoute // by ant number, built on the first PositionAt
+}
+
+var errNoPath = &codedError{code: codeNoPath}
+
+// ParseOptions control how strictly the input is read
+type ParseOptions struct {
+	// Strict rejects anything beyond the original subject, such as
+	// non-ASCII room names
+	Strict bool
+	// AllowZeroAnts accepts a farm with 0 ants, for callers that set the
+	// ant count themselves (--ants). Ignored in strict mode.
+	AllowZeroAnts bool
+	// Limits bound the size of the map (see defaultLimits)
+	Limits Limits
 }
 
 // ----- Parse input -----
-func parseInput(filename string) (*Farm, error) {
-	farm := &Farm{Rooms: make(map[string]*Room)}
+// Files ending in .lmb are read as binary farms (see convert).
+func parseInput(filename string, opts ParseOptions) (*Farm, error) {
+	if isBinaryFarm(filename) {
+		return loadBinaryFarm(filename, opts)
+	}
+	farm := newFarm()
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
+	limits := opts.Limits.withDefaults()
+	if err := checkFileSize(file, limits); err != nil {
+		return nil, err
+	}
 
 	startSet := false
 	endSet := false
 	scanner := bufio.NewScanner(file)
+	// The scanner only checks the maximum when it grows its buffer
+	bufSize := 64 * 1024
+	if limits.MaxLineLength+1 < bufSize {
+		bufSize = limits.MaxLineLength + 1
+	}
+	scanner.Buffer(make([]byte, 0, bufSize), limits.MaxLineLength+1)
+	tunnels := 0
 	var lastCmd string
 	lineCount := 0
 	coords := make(map[string]bool) // check duplicate coordinates
 
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+		// Normalized once, so that room names match wherever they appear
+		line := nfc(strings.TrimSpace(scanner.Text()))
 		if line == "" || (strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "##")) {
 			continue
 		}
 		if lineCount == 0 {
 			ants, err := strconv.Atoi(line)
-			if err != nil || ants <= 0 {
-				return nil, fmt.Errorf("invalid number of ants: %q", line)
+			minAnts := 1
+			if opts.AllowZeroAnts && !opts.Strict {
+				minAnts = 0
+			}
+			if err != nil || ants < minAnts {
+				return nil, codedErrorf(codeAnts, line)
 			}
 			farm.Ants = ants
 			lineCount++
 			continue
 		}
+		if opts.Strict && strings.Fields(line)[0] == "##spawn" {
+			return nil, codedErrorf(codeSpawnStrict, line)
+		}
+		if strings.HasPrefix(line, "##spawn") && !opts.Strict {
+			spawn, err := parseSpawn(line)
+			if err != nil {
+				return nil, err
+			}
+			farm.Spawns = append(farm.Spawns, spawn)
+			continue
+		}
+		// Other commands starting with ##zone are ignored like any unknown
+		// command, and so are zones in strict mode
+		if fields := strings.Fields(line); fields[0] == "##zone" && !opts.Strict {
+			if len(fields) < 3 {
+				return nil, codedErrorf(codeZoneLine, line)
+			}
+			farm.Zones = append(farm.Zones, Zone{Name: fields[1], Rooms: fields[2:]})
+			continue
+		}
 		if strings.HasPrefix(line, "##") {
 			lastCmd = line
 			continue
@@ -60,34 +201,43 @@ func parseInput(filename string) (*Farm, error) {
 		if strings.Contains(line, " ") {
 			parts := strings.Fields(line)
 			if len(parts) != 3 {
-				return nil, fmt.Errorf("invalid room definition: %q", line)
+				return nil, codedErrorf(codeRoomLine, line)
+			}
+			name, err := unquoteName(parts[0])
+			if err != nil {
+				return nil, err
+			}
+			if err := checkRoomName(name, opts); err != nil {
+				return nil, err
 			}
-			name := parts[0]
 			if _, exists := farm.Rooms[name]; exists {
-				return nil, fmt.Errorf("duplicate room name: %q", name)
+				return nil, codedErrorf(codeDuplicateRoom, name)
 			}
 			x, err1 := strconv.Atoi(parts[1])
 			y, err2 := strconv.Atoi(parts[2])
 			if err1 != nil || err2 != nil {
-				return nil, fmt.Errorf("invalid coordinates for room %q", name)
+				return nil, codedErrorf(codeCoordinates, name)
 			}
 			coordKey := fmt.Sprintf("%d-%d", x, y)
 			if coords[coordKey] {
-				return nil, fmt.Errorf("duplicate coordinates (%d,%d)", x, y)
+				return nil, codedErrorf(codeDuplicateCoords, x, y)
 			}
 			coords[coordKey] = true
-			farm.Rooms[name] = &Room{Name: name, X: x, Y: y}
+			if len(farm.Names) == limits.MaxRooms {
+				return nil, codedErrorf(codeTooManyRooms, limits.MaxRooms)
+			}
+			name = farm.addRoom(name, x, y).Name
 
 			if lastCmd == "##start" {
 				if startSet {
-					return nil, fmt.Errorf("more than one start room defined")
+					return nil, codedErrorf(codeTwoStarts)
 				}
 				farm.Start = name
 				startSet = true
 			}
 			if lastCmd == "##end" {
 				if endSet {
-					return nil, fmt.Errorf("more than one end room defined")
+					return nil, codedErrorf(codeTwoEnds)
 				}
 				farm.End = name
 				endSet = true
@@ -96,28 +246,64 @@ func parseInput(filename string) (*Farm, error) {
 			continue
 		}
 		if strings.Contains(line, "-") {
-			parts := strings.Split(line, "-")
-			if len(parts) != 2 {
-				return nil, fmt.Errorf("invalid tunnel line: %q", line)
+			a, b, err := splitTunnel(line, farm, opts)
+			if err != nil {
+				return nil, err
 			}
-			a, b := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
 			if farm.Rooms[a] == nil || farm.Rooms[b] == nil {
-				return nil, fmt.Errorf("tunnel references unknown room(s): %q", line)
+				return nil, codedErrorf(codeUnknownRoom, line)
+			}
+			if a == b {
+				return nil, codedErrorf(codeSelfTunnel, a)
+			}
+			if tunnels == limits.MaxTunnels {
+				return nil, codedErrorf(codeTooManyTunnels, limits.MaxTunnels)
 			}
-			farm.Rooms[a].Links = append(farm.Rooms[a].Links, b)
-			farm.Rooms[b].Links = append(farm.Rooms[b].Links, a)
+			tunnels++
+			farm.addTunnel(farm.Rooms[a], farm.Rooms[b])
 		} else {
-			return nil, fmt.Errorf("invalid line format: %q", line)
+			return nil, codedErrorf(codeLineFormat, line)
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, codedErrorf(codeLongLine, limits.MaxLineLength)
+		}
+		return nil, err
+	}
 
 	if farm.Start == "" || farm.End == "" {
-		return nil, fmt.Errorf("missing start or end room")
+		return nil, codedErrorf(codeNoStartEnd)
 	}
+
+	// Zones may list rooms defined later in the file
+	for _, zone := range farm.Zones {
+		for i, name := range zone.Rooms {
+			room := farm.Rooms[name]
+			if room == nil {
+				return nil, codedErrorf(codeZoneRoom, zone.Name, name)
+			}
+			if room.Zone != "" {
+				return nil, codedErrorf(codeTwoZones, name, room.Zone, zone.Name)
+			}
+			room.Zone = zone.Name
+			zone.Rooms[i] = room.Name
+		}
+	}
+
+	// Without tunnels at either end there is nothing to search for
+	for _, name := range []string{farm.Start, farm.End} {
+		if farm.Degree(name) == 0 {
+			return nil, noPathError(codeNoTunnels, name)
+		}
+	}
+	farm.Tunnels() // sort the tunnel list while the farm has a single owner
 	return farm, nil
 }
 
 // ----- Optimized BFS to find shortest path avoiding blocked rooms -----
+// Every queued path is held in memory; past maxFrontier of them the
+// search starts over with iterativeDeepening, which only keeps one path.
 func bfsShortestPath(f *Farm, startNeighbor string, blockedRooms map[string]bool) []string {
 	queue := [][]string{{f.Start, startNeighbor}}
 	visited := make(map[string]bool)
@@ -125,6 +311,10 @@ func bfsShortestPath(f *Farm, startNeighbor string, blockedRooms map[string]bool
 	visited[startNeighbor] = true
 
 	for len(queue) > 0 {
+		if maxFrontier > 0 && len(queue) > maxFrontier {
+			frontierFallbacks.Add(1)
+			return iterativeDeepening(f, startNeighbor, blockedRooms)
+		}
 		path := queue[0]
 		queue = queue[1:]
 		last := path[len(path)-1]
@@ -151,21 +341,35 @@ func findNonOverlappingPaths(f *Farm) [][]string {
 	var selectedPaths [][]string
 	blockedRooms := make(map[string]bool)
 
-	// Sort neighbors by number of links (try neighbors with fewer connections first)
+	// Sort neighbors by --heuristic (by default, try neighbors with fewer
+	// connections first)
 	neighbors := make([]string, len(f.Rooms[f.Start].Links))
 	copy(neighbors, f.Rooms[f.Start].Links)
 
-	// Simple sorting by number of links
-	for i := 0; i < len(neighbors)-1; i++ {
-		for j := i + 1; j < len(neighbors); j++ {
-			if len(f.Rooms[neighbors[j]].Links) < len(f.Rooms[neighbors[i]].Links) {
-				neighbors[i], neighbors[j] = neighbors[j], neighbors[i]
-			}
+	// Ties are broken by name, so the order does not depend on the input
+	score := heuristics[neighborHeuristic](f)
+	sort.SliceStable(neighbors, func(i, j int) bool {
+		if si, sj := score(neighbors[i]), score(neighbors[j]); si != sj {
+			return si < sj
 		}
-	}
+		return neighbors[i] < neighbors[j]
+	})
 
-	// Find path for each neighbor
+	// Find path for each neighbor. A neighbor listed twice (repeated
+	// tunnel) or already on a path would start a second path through the
+	// same first room, and the ants of both would take turns leaving.
+	// Past pathLimit every remaining search would fail, having gone
+	// through the whole farm first
+	limit := pathLimit(f)
+	seen := make(map[string]bool)
 	for _, neighbor := range neighbors {
+		if len(selectedPaths) == limit {
+			break
+		}
+		if seen[neighbor] || blockedRooms[neighbor] {
+			continue
+		}
+		seen[neighbor] = true
 		path := bfsShortestPath(f, neighbor, blockedRooms)
 		if path != nil {
 			selectedPaths = append(selectedPaths, path)
@@ -184,37 +388,13 @@ func findNonOverlappingPaths(f *Farm) [][]string {
 func findAllShortestPaths(f *Farm) [][]string {
 	var allPaths [][]string
 
+	seen := make(map[string]bool) // repeated tunnels give the same paths
 	for _, neighbor := range f.Rooms[f.Start].Links {
-		// Use BFS to find shortest path for this neighbor
-		queue := [][]string{{f.Start, neighbor}}
-		visited := make(map[string]bool)
-		visited[f.Start] = true
-		visited[neighbor] = true
-
-		var shortestPath []string
-
-		for len(queue) > 0 && shortestPath == nil {
-			path := queue[0]
-			queue = queue[1:]
-			current := path[len(path)-1]
-
-			if current == f.End {
-				shortestPath = path
-				break
-			}
-
-			for _, next := range f.Rooms[current].Links {
-				if !visited[next] {
-					visited[next] = true
-					newPath := make([]string, len(path))
-					copy(newPath, path)
-					newPath = append(newPath, next)
-					queue = append(queue, newPath)
-				}
-			}
+		if seen[neighbor] {
+			continue
 		}
-
-		if shortestPath != nil {
+		seen[neighbor] = true
+		if shortestPath := bfsShortestPath(f, neighbor, nil); shortestPath != nil {
 			allPaths = append(allPaths, shortestPath)
 		}
 	}
@@ -223,6 +403,19 @@ func findAllShortestPaths(f *Farm) [][]string {
 }
 
 // ----- Helper -----
+// pathLess orders paths by length, then by their room names
+func pathLess(a, b []string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
 // ----- Check if two paths share intermediate rooms -----
 func pathsShareRooms(path1, path2 []string) bool {
 	// Create set of intermediate rooms for path1
@@ -240,6 +433,36 @@ func pathsShareRooms(path1, path2 []string) bool {
 	return false
 }
 
+// ----- Keep only paths that share no room -----
+// Ants on two paths through the same rooms can block each other for good
+// (each waiting for the room the other one holds), leaving the simulation
+// with no move to make. Shorter paths are kept first; the others are
+// returned as dropped. Only one direct start-end path is kept.
+func disjointPaths(paths [][]string) (kept, dropped [][]string) {
+	order := make([]int, len(paths))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return pathLess(paths[order[i]], paths[order[j]]) })
+
+	used := make(map[string]bool)
+	keep := make([]bool, len(paths))
+	for _, i := range order {
+		if !pathUsesRooms(paths[i], used) {
+			setRooms(paths[i], used, true)
+			keep[i] = true
+		}
+	}
+	for i, path := range paths {
+		if keep[i] {
+			kept = append(kept, path)
+		} else {
+			dropped = append(dropped, path)
+		}
+	}
+	return kept, dropped
+}
+
 // ----- Select best non-conflicting paths -----
 func selectBestPaths(f *Farm, allPaths [][]string) [][]string {
 	if len(allPaths) == 0 {
@@ -247,13 +470,7 @@ func selectBestPaths(f *Farm, allPaths [][]string) [][]string {
 	}
 
 	// Sort paths by length (shortest first)
-	for i := 0; i < len(allPaths)-1; i++ {
-		for j := i + 1; j < len(allPaths); j++ {
-			if len(allPaths[j]) < len(allPaths[i]) {
-				allPaths[i], allPaths[j] = allPaths[j], allPaths[i]
-			}
-		}
-	}
+	sort.SliceStable(allPaths, func(i, j int) bool { return pathLess(allPaths[i], allPaths[j]) })
 
 	var selected [][]string
 	usedRooms := make(map[string]bool)
@@ -281,8 +498,37 @@ func selectBestPaths(f *Farm, allPaths [][]string) [][]string {
 	return selected
 }
 
+// ----- Assignment strategies: which ant takes which path -----
+// maxPerPath limits how many ants a single path may take (0: no limit);
+// callers check with checkCapacity that the paths can take every ant.
+type AssignmentStrategy func(ants int, paths [][]string, maxPerPath int) [][]int
+
+// Selected with --objective
+var objectives = map[string]AssignmentStrategy{
+	"turns":    distributeAnts,
+	"distance": distributeByDistance,
+}
+
+// Selected with --assign, in place of the objective's strategy; the
+// default, balanced, is the objective's
+var assignments = map[string]AssignmentStrategy{
+	"even": distributeEvenly,
+}
+
+// maxAntsPerPath is --max-per-path, set once from the command line before
+// any search runs, so that the searches score path sets under the cap
+var maxAntsPerPath int
+
+func checkCapacity(ants int, paths [][]string, maxPerPath int) error {
+	if maxPerPath > 0 && ants > maxPerPath*len(paths) {
+		return fmt.Errorf("%d paths with at most %d ants each cannot take %d ants", len(paths), maxPerPath, ants)
+	}
+	return nil
+}
 
-func distributeAnts(ants int, paths [][]string) [][]int {
+// Minimize the number of turns: each ant takes the path where it arrives
+// first, among the paths that are not full yet
+func distributeAnts(ants int, paths [][]string, maxPerPath int) [][]int {
 	lengths := make([]int, len(paths))
 	for i, p := range paths {
 		lengths[i] = len(p) - 1
@@ -292,121 +538,1245 @@ func distributeAnts(ants int, paths [][]string) [][]int {
 	assigned := make([]int, len(paths))
 
 	for a := 1; a <= ants; a++ {
-		best := 0
-		bestScore := lengths[0] + assigned[0]
-		for i := 1; i < len(paths); i++ {
+		best := -1
+		bestScore := 0
+		for i := 0; i < len(paths); i++ {
+			if maxPerPath > 0 && assigned[i] >= maxPerPath {
+				continue
+			}
 			score := lengths[i] + assigned[i]
-			if score < bestScore {
+			if best < 0 || score < bestScore {
 				best = i
 				bestScore = score
 			}
 		}
+		if best < 0 {
+			break
+		}
 		distribution[best] = append(distribution[best], a)
 		assigned[best]++
 	}
 	return distribution
 }
 
-func simulateAnts(paths [][]string, antDistribution [][]int) string {
-	var finalResult string
-	type AntPosition struct {
-		ant  int
-		path int
-		step int
+// Minimize the total distance travelled: every ant takes the shortest path
+// that is not full yet, however long the queue in front of it gets.
+func distributeByDistance(ants int, paths [][]string, maxPerPath int) [][]int {
+	distribution := make([][]int, len(paths))
+	order := make([]int, len(paths))
+	for i := range order {
+		order[i] = i
 	}
+	sort.SliceStable(order, func(i, j int) bool { return len(paths[order[i]]) < len(paths[order[j]]) })
 
-	var antPositions []AntPosition
+	next := 0
+	for a := 1; a <= ants; a++ {
+		for maxPerPath > 0 && next < len(order) && len(distribution[order[next]]) >= maxPerPath {
+			next++
+		}
+		if next == len(order) {
+			break
+		}
+		distribution[order[next]] = append(distribution[order[next]], a)
+	}
+	return distribution
+}
+
+// Use every path equally: the ants are dealt out over the paths in turn,
+// shortest first, so no two paths differ by more than one ant (the full
+// ones aside). Usually slower than "turns", but no path is left idle.
+func distributeEvenly(ants int, paths [][]string, maxPerPath int) [][]int {
+	distribution := make([][]int, len(paths))
+	order := make([]int, len(paths))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return len(paths[order[i]]) < len(paths[order[j]]) })
+
+	next := 0
+	for a := 1; a <= ants; a++ {
+		placed := false
+		for tried := 0; tried < len(order) && !placed; tried++ {
+			i := order[next]
+			next = (next + 1) % len(order)
+			if maxPerPath == 0 || len(distribution[i]) < maxPerPath {
+				distribution[i] = append(distribution[i], a)
+				placed = true
+			}
+		}
+		if !placed {
+			break
+		}
+	}
+	return distribution
+}
+
+// ----- Simulator: replays an ant distribution one turn at a time -----
+type antPosition struct {
+	ant  int
+	path int
+	step int
+}
+
+type Simulator struct {
+	paths     [][]string
+	positions []antPosition
+	occupied  map[string]bool // rooms holding an ant, besides start and end
+
+	// chaos mode: each possible move is skipped with this probability
+	chaos float64
+	rng   *rand.Rand
+
+	// reactive mode: ants wait in the start room until dispatch picks a path
+	reactive   bool
+	waiting    int
+	held       []int // waiting ants that already have a number, sent first
+	nextAnt    int
+	maxPerPath int
+	order      []int // path indices, shortest first
+	atStart    []int // dispatched ants still in the start room, per path
+
+	assigned [][]int // ants sent on each path so far
+}
+
+func newSimulator(paths [][]string, antDistribution [][]int) *Simulator {
+	s := &Simulator{paths: paths, occupied: make(map[string]bool), assigned: antDistribution}
 	for pathIndex, ants := range antDistribution {
 		for _, ant := range ants {
-			antPositions = append(antPositions, AntPosition{ant, pathIndex, 0})
+			s.positions = append(s.positions, antPosition{ant, pathIndex, 0})
 		}
 	}
-	for len(antPositions) > 0 {
-		var moves []string
-		var newPositions []AntPosition
-		usedLinks := make(map[string]bool)
+	return s
+}
+
+// newReactiveSimulator schedules ants online instead of following a
+// precomputed distribution: see dispatch.
+func newReactiveSimulator(paths [][]string, ants, maxPerPath int) *Simulator {
+	s := &Simulator{
+		paths:      paths,
+		occupied:   make(map[string]bool),
+		waiting:    ants,
+		nextAnt:    1,
+		maxPerPath: maxPerPath,
+		assigned:   make([][]int, len(paths)),
+	}
+	s.makeReactive()
+	return s
+}
+
+func (s *Simulator) makeReactive() {
+	s.reactive = true
+	s.atStart = make([]int, len(s.paths))
+	s.order = s.order[:0]
+	for i := range s.paths {
+		s.order = append(s.order, i)
+	}
+	sort.SliceStable(s.order, func(a, b int) bool {
+		return len(s.paths[s.order[a]]) < len(s.paths[s.order[b]])
+	})
+}
 
-		for _, pos := range antPositions {
-			if pos.step < len(paths[pos.path])-1 {
-				currentRoom := paths[pos.path][pos.step]
-				nextRoom := paths[pos.path][pos.step+1]
-				link := currentRoom + "-" + nextRoom
-				if !usedLinks[link] {
-					moves = append(moves, fmt.Sprintf("L%d-%s", pos.ant, nextRoom))
-					newPositions = append(newPositions, AntPosition{pos.ant, pos.path, pos.step + 1})
-					usedLinks[link] = true
-				} else {
-					newPositions = append(newPositions, pos)
+// AddAnts brings n more ants into the start room of a running simulation.
+// They are dispatched like in a reactive simulation; a simulator following
+// a planned distribution turns reactive, and its ants that have not left
+// yet are dispatched again along with the new ones (without the planned
+// --max-per-path, which it does not know). New ants are numbered after
+// all the others.
+func (s *Simulator) AddAnts(n int) {
+	if n <= 0 {
+		return
+	}
+	if !s.reactive {
+		s.nextAnt = 1
+		var moving []antPosition
+		for _, pos := range s.positions {
+			if pos.step == 0 {
+				s.held = append(s.held, pos.ant)
+			} else {
+				moving = append(moving, pos)
+			}
+		}
+		for _, group := range s.assigned {
+			for _, ant := range group {
+				if ant >= s.nextAnt {
+					s.nextAnt = ant + 1
+				}
+			}
+		}
+		sort.Ints(s.held)
+		held := make(map[int]bool, len(s.held))
+		for _, ant := range s.held {
+			held[ant] = true
+		}
+		assigned := make([][]int, len(s.paths))
+		for i, group := range s.assigned {
+			for _, ant := range group {
+				if !held[ant] {
+					assigned[i] = append(assigned[i], ant)
 				}
 			}
 		}
+		s.positions, s.assigned = moving, assigned
+		s.waiting = len(s.held)
+		s.makeReactive()
+	}
+	s.waiting += n
+}
+
+// takeAnt returns the number of the next ant to send
+func (s *Simulator) takeAnt() int {
+	s.waiting--
+	if len(s.held) > 0 {
+		ant := s.held[0]
+		s.held = s.held[1:]
+		return ant
+	}
+	s.nextAnt++
+	return s.nextAnt - 1
+}
+
+// dispatch sends waiting ants into every path whose entrance is free.
+// Path i (by length L, shortest first) only gets an ant when more than
+// sum_{j<i}(L_i - L_j) ants are still waiting: with fewer, each of them
+// arrives sooner by queueing for a shorter path. On a static map this
+// gives the same turn count as distributeAnts.
+func (s *Simulator) dispatch() {
+	for k, i := range s.order {
+		if s.full(i) || s.atStart[i] > 0 {
+			continue
+		}
+		length := len(s.paths[i]) - 1
+		sooner := 0 // ants the shorter paths can still bring in before this one
+		for _, j := range s.order[:k] {
+			n := length - (len(s.paths[j]) - 1)
+			if s.maxPerPath > 0 && s.maxPerPath-len(s.assigned[j]) < n {
+				n = s.maxPerPath - len(s.assigned[j])
+			}
+			sooner += n
+		}
+		if s.waiting > sooner {
+			ant := s.takeAnt()
+			s.positions = append(s.positions, antPosition{ant, i, 0})
+			s.assigned[i] = append(s.assigned[i], ant)
+			s.atStart[i]++
+		}
+		if s.waiting == 0 {
+			return
+		}
+	}
+}
+
+func (s *Simulator) full(path int) bool {
+	return s.maxPerPath > 0 && len(s.assigned[path]) >= s.maxPerPath
+}
+
+// Distribution returns the ants sent on each path. For a reactive
+// simulator it is only complete once every ant has been dispatched.
+func (s *Simulator) Distribution() [][]int {
+	return s.assigned
+}
+
+// enableChaos makes every move fail with probability p (seeded, so runs
+// can be reproduced). Ants then wait where they are and the ones behind
+// them queue up, so all ants still arrive eventually.
+func (s *Simulator) enableChaos(p float64, seed int64) {
+	s.chaos = p
+	s.rng = rand.New(rand.NewSource(seed))
+}
+
+// Step moves every ant that can move and returns the resulting turn.
+// It returns false once all ants have reached the end room.
+//
+// An ant moves when the tunnel in front of it is free this turn and the
+// next room is empty (or is the end). Ants are handled front first, so a
+// room left by an ant can be entered by the one behind it in the same turn.
+//
+// Every turn returned moves at least one ant, so a schedule has no idle
+// turn. The one exception is spawnSteps around Step: while no ant is on
+// the way and the next ones have not spawned yet, its turns are empty.
+func (s *Simulator) Step() (Turn, bool) {
+	for len(s.positions) > 0 || s.waiting > 0 {
+		if s.reactive && s.waiting > 0 {
+			s.dispatch()
+		}
+		var moves Turn
+		var newPositions []antPosition
+		usedLinks := make(map[string]bool)
+		canMove := 0
+
+		for _, pos := range s.positions {
+			path := s.paths[pos.path]
+			currentRoom := path[pos.step]
+			nextRoom := path[pos.step+1]
+			arriving := pos.step+1 == len(path)-1
+			link := currentRoom + "-" + nextRoom
+			linkBusy := usedLinks[link] || injectedLinkBusy(pos.ant, currentRoom, nextRoom)
+			roomBusy := !arriving && (s.occupied[nextRoom] || injectedRoomOccupied(pos.ant, nextRoom))
+			if linkBusy || roomBusy {
+				newPositions = append(newPositions, pos)
+				continue
+			}
+			canMove++
+			if s.chaos > 0 && s.rng.Float64() < s.chaos {
+				newPositions = append(newPositions, pos)
+				continue
+			}
+			usedLinks[link] = true
+			if s.reactive && pos.step == 0 {
+				s.atStart[pos.path]--
+			}
+			delete(s.occupied, currentRoom)
+			moves = append(moves, Move{Ant: pos.ant, Room: nextRoom, Path: pos.path})
+			if !arriving {
+				s.occupied[nextRoom] = true
+				newPositions = append(newPositions, antPosition{pos.ant, pos.path, pos.step + 1})
+			}
+		}
+		s.positions = newPositions
 		if len(moves) > 0 {
-			finalResult += strings.Join(moves, " ")
-			finalResult += "\n"
+			return moves, true
+		}
+		// A turn where every move was skipped by chaos is replayed. One
+		// where no ant could move at all would repeat forever: that cannot
+		// happen on paths sharing no room (see disjointPaths), where the
+		// ant furthest along a path can always move
+		if canMove == 0 {
+			panic("simulation stuck: no ant can move")
 		}
-		antPositions = newPositions
 	}
-	return finalResult
+	return nil, false
 }
 
+// ----- Predict the turns needed by the balanced distribution -----
+// The k-th ant on a path of n tunnels arrives on turn n+k-1. Paths take at
+// most maxPerPath ants each (0: no limit); a set that cannot take every ant
+// needs unreachableTurns, more than any set that can.
+const unreachableTurns = 1<<31 - 1
 
-// ----- MAIN -----
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run . input.txt")
-		return
+func predictTurns(paths [][]string, ants, maxPerPath int) int {
+	if checkCapacity(ants, paths, maxPerPath) != nil {
+		return unreachableTurns
 	}
-	filename := os.Args[1]
-	farm, err := parseInput(filename)
-	if err != nil {
-		fmt.Println("Error:", err)
-		return
+	turns := 0
+	for i, group := range distributeAnts(ants, paths, maxPerPath) {
+		if t := len(paths[i]) - 1 + len(group) - 1; len(group) > 0 && t > turns {
+			turns = t
+		}
+	}
+	return turns
+}
+
+// ----- Solve a farm and count the turns, without any output -----
+func solveQuietly(f *Farm, strategy Strategy) ([][]string, int, error) {
+	paths, _ := disjointPaths(strategy(f))
+	if len(paths) == 0 {
+		return nil, 0, errNoPath
+	}
+	return paths, countTurns(newSimulator(paths, distributeAnts(f.Ants, paths, 0))), nil
+}
+
+// countTurns runs a simulation to the end without keeping the turns
+func countTurns(sim *Simulator) int {
+	turns := 0
+	for {
+		if _, ok := sim.Step(); !ok {
+			return turns
+		}
+		turns++
+	}
+}
+
+// ----- Throughput: how many ants reach the end within a turn budget -----
+// The k-th ant sent over a path of n tunnels arrives on turn n+k-1, so the
+// path delivers at most budget-n+1 ants, and never more than maxPerPath.
+func antsWithinTurns(paths [][]string, budget, maxPerPath int) int {
+	total := 0
+	for _, p := range paths {
+		n := budget - (len(p) - 1) + 1
+		if maxPerPath > 0 && n > maxPerPath {
+			n = maxPerPath
+		}
+		if n > 0 {
+			total += n
+		}
+	}
+	return total
+}
+
+func simulateAnts(paths [][]string, antDistribution [][]int) []Turn {
+	return collectTurns(newSimulator(paths, antDistribution).Step)
+}
+
+func collectTurns(next func() (Turn, bool)) []Turn {
+	var turns []Turn
+	for {
+		turn, ok := next()
+		if !ok {
+			return turns
+		}
+		turns = append(turns, turn)
+	}
+}
+
+// ----- Format turns as move lines -----
+// turnWriter writes one turn, with its newline
+type turnWriter func(w io.Writer, turn Turn) error
+
+// writeTurn builds the line by hand: with fmt, formatting the moves took
+// most of the time of big runs. Written to a bufio.Writer, as streamAnts
+// does, the line goes straight into its buffer without allocating.
+func writeTurn(w io.Writer, turn Turn) error {
+	return writeLine(w, func(b []byte) []byte {
+		for i, m := range turn {
+			if i > 0 {
+				b = append(b, ' ')
+			}
+			b = appendMove(b, m)
+		}
+		return b
+	})
+}
+
+// appendMove appends "L<ant>-<room>"
+func appendMove(b []byte, m Move) []byte {
+	b = append(b, 'L')
+	b = strconv.AppendInt(b, int64(m.Ant), 10)
+	b = append(b, '-')
+	return append(b, m.Room...)
+}
+
+// writeLine writes what build appends, then a newline, reusing the free
+// space of w's buffer when w is a bufio.Writer
+func writeLine(w io.Writer, build func(b []byte) []byte) error {
+	var b []byte
+	if bw, ok := w.(*bufio.Writer); ok {
+		b = bw.AvailableBuffer()
+	}
+	b = append(build(b), '\n')
+	_, err := w.Write(b)
+	return err
+}
+
+// groupedByPath writes the moves of each turn path by path (in path
+// order, keeping the order within a path), with the path number in
+// brackets before each group when labels is set.
+func groupedByPath(labels bool) turnWriter {
+	var grouped Turn // reused from turn to turn
+	return func(w io.Writer, turn Turn) error {
+		grouped = append(grouped[:0], turn...)
+		sort.Stable(byPath(grouped))
+		if !labels {
+			return writeTurn(w, grouped)
+		}
+		return writeLine(w, func(b []byte) []byte {
+			for i, m := range grouped {
+				if i > 0 {
+					b = append(b, ' ')
+				}
+				if i == 0 || m.Path != grouped[i-1].Path {
+					b = append(b, '[')
+					b = strconv.AppendInt(b, int64(m.Path+1), 10)
+					b = append(b, "] "...)
+				}
+				b = appendMove(b, m)
+			}
+			return b
+		})
+	}
+}
+
+// following writes only the moves for which keep is true, each kept turn
+// after its number, so that one ant or path can be traced in a big output
+// (--follow, --follow-path). Turns without such a move are left out.
+func following(write turnWriter, keep func(Move) bool) turnWriter {
+	turnNumber := 0
+	return func(w io.Writer, turn Turn) error {
+		turnNumber++
+		var kept Turn
+		for _, m := range turn {
+			if keep(m) {
+				kept = append(kept, m)
+			}
+		}
+		if len(kept) == 0 {
+			return nil
+		}
+		if _, err := fmt.Fprintf(w, "%d: ", turnNumber); err != nil {
+			return err
+		}
+		return write(w, kept)
+	}
+}
+
+type byPath Turn
+
+func (t byPath) Len() int           { return len(t) }
+func (t byPath) Less(i, j int) bool { return t[i].Path < t[j].Path }
+func (t byPath) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+
+// ----- Check if two solutions are equivalent -----
+// Two turns are equal when they contain the same moves, regardless of order.
+func sameTurn(a, b Turn) bool {
+	if len(a) != len(b) {
+		return false
 	}
+	// Path is left out: it is only known for simulated turns
+	moves := make(map[Move]int)
+	for _, m := range a {
+		moves[Move{Ant: m.Ant, Room: m.Room}]++
+	}
+	for _, m := range b {
+		key := Move{Ant: m.Ant, Room: m.Room}
+		if moves[key] == 0 {
+			return false
+		}
+		moves[key]--
+	}
+	return true
+}
 
-	fmt.Printf("Farm: %d ants, start=%s, end=%s\n", farm.Ants, farm.Start, farm.End)
-	fmt.Printf("Start room has %d neighbors: %v\n", len(farm.Rooms[farm.Start].Links), farm.Rooms[farm.Start].Links)
+// Two solutions are equal when they have the same number of turns and
+// every turn contains the same moves. sameSimulation checks it stepping
+// both simulators in lockstep, so neither schedule has to be kept in
+// memory, and returns the turn count.
+func sameSimulation(a, b *Simulator) (bool, int) {
+	turns := 0
+	for {
+		ta, okA := a.Step()
+		tb, okB := b.Step()
+		if okA != okB || !sameTurn(ta, tb) {
+			return false, turns
+		}
+		if !okA {
+			return true, turns
+		}
+		turns++
+	}
+}
+
+// replayTurns steps through turns that were already computed
+func replayTurns(turns []Turn) func() (Turn, bool) {
+	return func() (Turn, bool) {
+		if len(turns) == 0 {
+			return nil, false
+		}
+		turn := turns[0]
+		turns = turns[1:]
+		return turn, true
+	}
+}
 
+// ----- Stream the simulation to the output -----
+// Turns are written as soon as they are computed; a write error (such as
+// a closed pipe when the output goes to `head`) or Ctrl-C stops the simulation.
+// observe, when set, is called with every emitted turn.
+// next is usually a Simulator's Step method.
+func streamAnts(w io.Writer, write turnWriter, next func() (Turn, bool), prog *progress, observe func(Turn)) error {
+	out := bufio.NewWriter(w)
+	for !prog.interrupted.Load() {
+		turn, ok := next()
+		if !ok {
+			return out.Flush()
+		}
+		if err := write(out, turn); err != nil {
+			return err
+		}
+		if observe != nil {
+			observe(turn)
+		}
+		prog.turns.Add(1)
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	return errInterrupted
+}
+
+// A teeWriter writes to stdout as well as to the --out file, and keeps
+// filling the file when stdout goes away (say, piped into head).
+type teeWriter struct {
+	file      io.Writer
+	stdout    io.Writer
+	stdoutErr error
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	n, err := t.file.Write(p)
+	if err == nil && t.stdoutErr == nil {
+		_, t.stdoutErr = t.stdout.Write(p)
+	}
+	return n, err
+}
+
+// ----- Compare the path-finding methods and report each of them (-v) -----
+func comparePaths(diag io.Writer, farm *Farm, assign AssignmentStrategy) [][]string {
 	// Method 1: Find all shortest paths first
-	fmt.Println("\n=== Finding all shortest paths ===")
+	fmt.Fprintln(diag, "\n=== Finding all shortest paths ===")
 	allPaths := findAllShortestPaths(farm)
-	fmt.Printf("Found %d shortest paths:\n", len(allPaths))
-	for i, p := range allPaths {
-		fmt.Printf("Path %d: %v (length: %d)\n", i+1, p, len(p))
-	}
+	fmt.Fprintf(diag, "Found %d shortest paths:\n", len(allPaths))
+	writePaths(diag, allPaths)
 
 	// Method 2: Select non-conflicting paths
-	fmt.Println("\n=== Selecting non-conflicting paths ===")
+	fmt.Fprintln(diag, "\n=== Selecting non-conflicting paths ===")
 	bestPaths := selectBestPaths(farm, allPaths)
-	fmt.Printf("Selected %d non-conflicting paths:\n", len(bestPaths))
-	for i, p := range bestPaths {
-		fmt.Printf("Path %d: %v (length: %d)\n", i+1, p, len(p))
-	}
+	fmt.Fprintf(diag, "Selected %d non-conflicting paths:\n", len(bestPaths))
+	writePaths(diag, bestPaths)
 
 	// Method 3: Find non-overlapping paths directly
-	fmt.Println("\n=== Finding non-overlapping paths directly ===")
+	fmt.Fprintln(diag, "\n=== Finding non-overlapping paths directly ===")
 	nonOverlapPaths := findNonOverlappingPaths(farm)
-	fmt.Printf("Found %d non-overlapping paths:\n", len(nonOverlapPaths))
-	for i, p := range nonOverlapPaths {
-		fmt.Printf("Path %d: %v (length: %d)\n", i+1, p, len(p))
+	fmt.Fprintf(diag, "Found %d non-overlapping paths:\n", len(nonOverlapPaths))
+
+	// Collapse the report when both methods lead to the same moves
+	same, turns := sameSimulation(
+		newSimulator(bestPaths, assign(farm.Ants, bestPaths, 0)),
+		newSimulator(nonOverlapPaths, assign(farm.Ants, nonOverlapPaths, 0)))
+	if len(bestPaths) > 0 && same {
+		fmt.Fprintf(diag, "Same solution as the non-conflicting paths (%d turns)\n", turns)
+	} else {
+		writePaths(diag, nonOverlapPaths)
+	}
+
+	nonOverlapPaths, _ = disjointPaths(nonOverlapPaths)
+	if endIsBottleneck(farm) {
+		fmt.Fprintln(diag, "\n=== Searching backwards from the end ===")
+		var back [][]string
+		var replaced bool
+		nonOverlapPaths, back, replaced = preferBackward(farm, nonOverlapPaths)
+		fmt.Fprintf(diag, "Found %d paths from the end:\n", len(back))
+		writePaths(diag, back)
+		if replaced {
+			fmt.Fprintln(diag, "They replace the non-overlapping paths")
+		}
+	}
+	paths, reason := pickPaths(farm.Ants, maxAntsPerPath, nonOverlapPaths, bestPaths)
+	fmt.Fprintf(diag, "\nPicked %s\n", reason)
+	return paths
+}
+
+// ----- Command line options -----
+
+// Set by selfcheck.go in builds made with -tags selfcheck (CI, tests)
+var defaultSelfCheck = false
+
+type options struct {
+	verbose      bool
+	ants         int
+	stats        bool
+	memStats     bool
+	strict       bool
+	prune        bool
+	subgraph     string
+	selfCheck    bool
+	chaos        float64
+	seed         int64
+	runs         int
+	version      bool
+	versionJSON  bool
+	objective    string
+	assign       string
+	maxPerPath   int
+	turns        int
+	algo         string
+	plugin       string
+	scheduler    string
+	format       string
+	replay       string
+	smooth       bool
+	groupBy      string
+	pathLabels   bool
+	count        bool
+	reversePaths bool
+	countMoves   bool
+	from         string
+	nodes        string
+	limits       string
+	compact      bool
+	frontier     int
+	traceAlgo    string
+	heuristic    string
+	lang         string
+	out          string
+	tee          bool
+	visualizer   bool
+	arrivals     string
+	chart        string
+	follow       string
+	followPath   int
+}
+
+// parseFlags parses flags placed anywhere on the command line and returns
+// the remaining positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// ----- MAIN -----
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "simulate":
+			runSimulate(os.Args[2:])
+			return
+		case "verify":
+			runVerify(os.Args[2:])
+			return
+		case "batch":
+			runBatch(os.Args[2:])
+			return
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "analyze":
+			runAnalyze(os.Args[2:])
+			return
+		case "rate":
+			runRate(os.Args[2:])
+			return
+		case "convert":
+			runConvert(os.Args[2:])
+			return
+		case "replay":
+			runReplay(os.Args[2:])
+			return
+		case "fix":
+			runFix(os.Args[2:])
+			return
+		case "vectors":
+			runVectors(os.Args[2:])
+			return
+		case "optimum":
+			runOptimum(os.Args[2:])
+			return
+		case "anonymize":
+			runAnonymize(os.Args[2:])
+			return
+		case "merge":
+			runMerge(os.Args[2:])
+			return
+		case "minimize":
+			runMinimize(os.Args[2:])
+			return
+		case "bench":
+			runBench(os.Args[2:])
+			return
+		case "scenario":
+			runScenario(os.Args[2:])
+			return
+		}
+	}
+
+	var opts options
+	fs := flag.NewFlagSet("lem-in", flag.ExitOnError)
+	fs.BoolVar(&opts.stats, "stats", false, "print statistics to stderr after the simulation")
+	fs.BoolVar(&opts.verbose, "v", false, "print path-finding diagnostics to stderr")
+	fs.IntVar(&opts.ants, "ants", -1, "number of ants, replacing the count from the map (which may then be 0)")
+	fs.BoolVar(&opts.prune, "prune-unreachable", false, "drop rooms that cannot be reached from start before solving")
+	fs.StringVar(&opts.subgraph, "extract-solution-subgraph", "", "also write a map of only the rooms and tunnels of the chosen paths to this file")
+	fs.BoolVar(&opts.selfCheck, "self-check", defaultSelfCheck, "check the schedule against the rules before printing it")
+	fs.Float64Var(&opts.chaos, "chaos", 0, "skip each move with this probability, to test recovery (0 <= p < 1)")
+	fs.Int64Var(&opts.seed, "seed", 1, "random seed for --chaos, and the first seed of --runs")
+	fs.IntVar(&opts.runs, "runs", 0, "solve N times with shuffled tunnels, report the min, median and max turns and keep the best")
+	fs.BoolVar(&opts.strict, "strict", false, "only accept maps that follow the original subject exactly, and print the map before the moves as it does")
+	fs.BoolVar(&opts.memStats, "mem-stats", false, "print heap usage per phase to stderr")
+	fs.StringVar(&opts.objective, "objective", "turns", "what to minimize: turns or distance")
+	fs.StringVar(&opts.assign, "assign", "balanced", "how to hand out the ants: balanced (for --objective) or even (the same number on every path, give or take one)")
+	fs.StringVar(&opts.algo, "algo", envDefault("LEMIN_ALGO", "auto"), "path-finding strategy: "+strings.Join(strategyNames(), ", "))
+	fs.StringVar(&opts.plugin, "plugin", "", "load extra strategies from a Go plugin (.so)")
+	fs.IntVar(&opts.maxPerPath, "max-per-path", 0, "at most this many ants on a single path (0: no limit)")
+	fs.IntVar(&opts.turns, "turns", 0, "only report how many ants can reach the end within this many turns")
+	fs.StringVar(&opts.format, "format", envDefault("LEMIN_FORMAT", "text"), "output format: "+strings.Join(encoderNames(), ", ")+" (text: one line of moves per turn, jsonl: one JSON object per turn)")
+	fs.BoolVar(&opts.compact, "compact", false, "move ants that could leave earlier forward, and report the turns saved")
+	fs.StringVar(&opts.heuristic, "heuristic", "degree", "order of the start neighbours for the non-overlapping search: "+strings.Join(heuristicNames(), ", "))
+	fs.StringVar(&opts.lang, "lang", language, "language of error messages: en or fr (default from LANG)")
+	fs.StringVar(&opts.traceAlgo, "trace-algo", "", "with --algo flow, write every augmentation as a DOT frame into this directory")
+	fs.IntVar(&opts.frontier, "max-frontier", 0, "search with iterative deepening once a BFS holds more than N paths (0: no limit)")
+	fs.StringVar(&opts.limits, "limits", "", "parser limits, e.g. rooms=5000,tunnels=20000,line=256,size=1048576")
+	fs.StringVar(&opts.from, "from", "text", "input format: text or edgelist (CSV, with --nodes and --ants)")
+	fs.StringVar(&opts.nodes, "nodes", "", "node table of an edge list input")
+	fs.BoolVar(&opts.count, "count", false, "print only the number of turns instead of the moves")
+	fs.BoolVar(&opts.countMoves, "count-moves", false, "like --count, followed by the total number of moves")
+	fs.StringVar(&opts.follow, "follow", "", "only print the moves of this ant, e.g. L37, after their turn number")
+	fs.IntVar(&opts.followPath, "follow-path", 0, "only print the moves along this path (numbered from 1), after their turn number")
+	fs.StringVar(&opts.groupBy, "group-by", "", "order the moves of each turn: path (by path number)")
+	fs.BoolVar(&opts.reversePaths, "reverse-paths", false, "list the paths of -v from end to start (the moves are unchanged)")
+	fs.BoolVar(&opts.pathLabels, "path-labels", false, "with --group-by path, write the path number before each group")
+	fs.BoolVar(&opts.smooth, "smooth", false, "among paths of equal length, prefer the ones with the shortest drawn length")
+	fs.StringVar(&opts.out, "out", "", "write the solution to this file instead of stdout")
+	fs.BoolVar(&opts.tee, "tee", false, "with --out, also write the solution to stdout")
+	fs.StringVar(&opts.arrivals, "arrivals", "", "write an \"ant turn\" line to this file for every ant reaching the end")
+	fs.StringVar(&opts.chart, "chart", "", "write an SVG bar chart of the path lengths and ants per path to this file")
+	fs.BoolVar(&opts.visualizer, "visualizer-compat", false, "print the map exactly as read, a blank line and the moves, as the lem-in visualizers expect (--format visualizer)")
+	fs.StringVar(&opts.replay, "replay", "", "also write a compressed replay of the moves to this file")
+	fs.BoolVar(&opts.version, "version", false, "print the version, build information and the strategies and formats available, then exit")
+	fs.BoolVar(&opts.versionJSON, "json", false, "with --version, print it as JSON")
+	fs.StringVar(&opts.scheduler, "scheduler", "static", "static (plan the distribution first) or reactive (dispatch ants turn by turn)")
+	args, _ := parseFlags(fs, os.Args[1:])
+	if opts.versionJSON && !opts.version {
+		reportErrorf("--json needs --version")
+		return
+	}
+	if opts.version {
+		if opts.plugin != "" {
+			if err := loadPlugin(opts.plugin); err != nil {
+				reportError(err)
+				return
+			}
+		}
+		if err := writeVersion(os.Stdout, opts.versionJSON); err != nil {
+			reportError(err)
+		}
+		return
+	}
+	if len(args) < 1 {
+		fmt.Println("Usage: go run . [-v] [--ants N] [--strict] [--prune-unreachable] [--extract-solution-subgraph out.txt] [--self-check] [--chaos p [--seed N]] [--runs N [--seed N]] [--stats] [--mem-stats] [--algo name] [--plugin file.so] [--objective turns|distance] [--assign balanced|even] [--max-per-path K] [--turns T] [--scheduler static|reactive] [--format text|json|jsonl|csv|visualizer] [--visualizer-compat] [--replay file] [--out file [--tee]] [--arrivals file] [--chart file.svg] [--smooth] [--reverse-paths] [--group-by path [--path-labels]] [--follow Ln|--follow-path N] [--count|--count-moves] [--from edgelist --nodes nodes.csv] [--limits k=N,...] [--compact] [--max-frontier N] [--algo flow --trace-algo dir] [--heuristic name] [--lang en|fr] input.txt")
+		fmt.Println("       go run . --version [--json] [--plugin file.so]")
+		fmt.Println("       go run . simulate input.txt --paths paths.json [--ants N]")
+		fmt.Println("       go run . verify input.txt [--max-rooms N] [--max-sets N] [--reverse-paths]")
+		fmt.Println("       go run . batch [--workers N] [--algo name] [--cache N [--cache-dir dir]] map1.txt map2.txt ...")
+		fmt.Println("       go run . diff old.txt new.txt")
+		fmt.Println("       go run . analyze input.txt")
+		fmt.Println("       go run . rate input.txt")
+		fmt.Println("       go run . convert [--to text|lmb|edgelist|adjmatrix] input.txt output")
+		fmt.Println("       go run . replay file")
+		fmt.Println("       go run . fix input.txt")
+		fmt.Println("       go run . vectors [--out dir] [--max-rooms N] map1.txt ...")
+		fmt.Println("       go run . optimum [--max-nodes N] input.txt")
+		fmt.Println("       go run . anonymize [--mapping file | --reverse file] input.txt")
+		fmt.Println("       go run . merge [--bridge a=b,...] [--join a=b,...] [--prefix p] first.txt second.txt")
+		fmt.Println("       go run . minimize input.txt --while condition [--algo name]")
+		fmt.Println("       go run . bench [--heuristics a,b,...] [--algo name] map1.txt map2.txt ...")
+		fmt.Println("       go run . scenario scenario.txt")
+		return
+	}
+	// Set first, so that the errors about the other options are translated
+	if catalogs[opts.lang] == nil {
+		reportErrorf("unknown language %q, use en or fr", opts.lang)
+		return
+	}
+	language = opts.lang
+	// A LEMIN_FORMAT default gives way to the options that only print text
+	formatSet, objectiveSet, antsSet := false, false, false
+	fs.Visit(func(f *flag.Flag) {
+		formatSet = formatSet || f.Name == "format"
+		objectiveSet = objectiveSet || f.Name == "objective"
+		antsSet = antsSet || f.Name == "ants"
+	})
+	if !formatSet && (opts.count || opts.countMoves || opts.follow != "" || opts.followPath != 0) {
+		opts.format = "text"
+	}
+	if opts.visualizer {
+		if formatSet && opts.format != "visualizer" {
+			reportErrorf("--visualizer-compat cannot be combined with --format %s", opts.format)
+			return
+		}
+		opts.format = "visualizer"
+	}
+	if opts.chaos < 0 || opts.chaos >= 1 {
+		reportErrorf("--chaos must be at least 0 and below 1")
+		return
+	}
+	assign, ok := objectives[opts.objective]
+	if !ok {
+		reportErrorf("unknown objective %q", opts.objective)
+		return
+	}
+	if opts.assign != "balanced" {
+		if assign, ok = assignments[opts.assign]; !ok {
+			reportErrorf("unknown --assign %q, use balanced or even", opts.assign)
+			return
+		}
+		if objectiveSet {
+			reportErrorf("--assign %s does not follow an objective, so it cannot be combined with --objective", opts.assign)
+			return
+		}
+	}
+	switch {
+	case opts.scheduler != "static" && opts.scheduler != "reactive":
+		reportErrorf("unknown scheduler %q", opts.scheduler)
+		return
+	case opts.scheduler == "reactive" && (opts.objective != "turns" || opts.assign != "balanced"):
+		reportErrorf("the reactive scheduler only minimizes turns")
+		return
+	case encoders[opts.format] == nil:
+		reportErrorf("unknown format %q, use one of %s", opts.format, strings.Join(encoderNames(), ", "))
+		return
+	case opts.from == "edgelist" && !antsSet:
+		reportErrorf("an edge list has no ant count: set it with --ants")
+		return
+	case antsSet && opts.ants < 0:
+		reportErrorf("--ants must not be negative")
+		return
+	case antsSet && opts.ants == 0 && opts.strict:
+		reportErrorf("the subject's format needs at least one ant, so --strict cannot take --ants 0")
+		return
+	case (opts.count || opts.countMoves) && opts.format != "text":
+		reportErrorf("--count cannot be combined with --format %s", opts.format)
+		return
+	case opts.groupBy != "" && opts.groupBy != "path":
+		reportErrorf("unknown --group-by %q", opts.groupBy)
+		return
+	case opts.pathLabels && opts.groupBy != "path":
+		reportErrorf("--path-labels needs --group-by path")
+		return
+	case opts.pathLabels && opts.strict:
+		reportErrorf("--path-labels writes moves outside the subject's format, so it cannot be combined with --strict")
+		return
+	case opts.replay != "" && opts.chaos > 0:
+		reportErrorf("--replay cannot record a --chaos run, where ants wait on the way")
+		return
+	case opts.frontier < 0:
+		reportErrorf("--max-frontier must not be negative")
+		return
+	case opts.traceAlgo != "" && opts.algo != "flow":
+		reportErrorf("--trace-algo needs --algo flow")
+		return
+	case heuristics[opts.heuristic] == nil:
+		reportErrorf("unknown heuristic %q", opts.heuristic)
+		return
+	case opts.follow != "" && opts.followPath != 0:
+		reportErrorf("--follow cannot be combined with --follow-path")
+		return
+	case (opts.follow != "" || opts.followPath != 0) && (opts.format != "text" || opts.strict || opts.count || opts.countMoves):
+		reportErrorf("--follow and --follow-path only filter the text output, without --strict or --count")
+		return
+	case opts.followPath < 0:
+		reportErrorf("--follow-path must be a path number from 1")
+		return
+	case opts.runs < 0:
+		reportErrorf("--runs must be at least 1")
+		return
+	case opts.format == "visualizer" && antsSet:
+		reportErrorf("--visualizer-compat prints the map as read, so it cannot take --ants")
+		return
+	case opts.format == "visualizer" && (opts.groupBy != "" || opts.pathLabels):
+		reportErrorf("--visualizer-compat writes the moves as the visualizers read them, so it cannot take --group-by or --path-labels")
+		return
+	case opts.tee && opts.out == "":
+		reportErrorf("--tee needs --out")
+		return
+	}
+	followAnt := 0
+	if opts.follow != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(opts.follow, "L"))
+		if err != nil || n < 1 {
+			reportErrorf("invalid --follow %q, expected an ant such as L37", opts.follow)
+			return
+		}
+		followAnt = n
+	}
+	maxFrontier = opts.frontier
+	maxAntsPerPath = opts.maxPerPath
+	neighborHeuristic = opts.heuristic
+	reversePaths = opts.reversePaths
+	if opts.plugin != "" {
+		if err := loadPlugin(opts.plugin); err != nil {
+			reportError(err)
+			return
+		}
+	}
+	strategy, err := lookupStrategy(opts.algo)
+	if err != nil {
+		reportError(err)
+		return
+	}
+	if opts.traceAlgo != "" {
+		if err := startFlowTrace(opts.traceAlgo); err != nil {
+			reportError(err)
+			return
+		}
+	}
+	filename := args[0]
+	prog := newProgress()
+	prog.memStats = opts.memStats
+	handleInterrupt(prog)
+	defer recoverInternalError(filename, prog)
+
+	prog.enter("parse")
+	limits, err := parseLimits(opts.limits)
+	if err != nil {
+		reportError(err)
+		return
+	}
+	parseOpts := ParseOptions{Strict: opts.strict, AllowZeroAnts: antsSet, Limits: limits}
+	farm, err := loadFarm(filename, opts.from, opts.nodes, parseOpts)
+	if err != nil {
+		reportError(err)
+		return
+	}
+	if antsSet {
+		farm.Ants = opts.ants
+	}
+	if len(farm.Spawns) > 0 && (opts.compact || opts.replay != "" || opts.turns > 0) {
+		reportErrorf("--compact, --replay and --turns do not support maps with ##spawn")
+		return
+	}
+	// The visualizers only know the ants of the first line
+	if len(farm.Spawns) > 0 && opts.format == "visualizer" {
+		reportErrorf("--visualizer-compat does not support maps with ##spawn")
+		return
+	}
+	var input []byte
+	if opts.format == "visualizer" && opts.from == "text" && !isBinaryFarm(filename) {
+		if input, err = os.ReadFile(filename); err != nil {
+			reportError(err)
+			return
+		}
+	}
+
+	// Diagnostics only with -v, on stderr so they never mix with the moves
+	var diag io.Writer = io.Discard
+	if opts.verbose {
+		diag = os.Stderr
+	}
+	if opts.prune {
+		var rooms, tunnels int
+		farm, rooms, tunnels, err = pruneUnreachable(farm)
+		if err != nil {
+			reportError(err)
+			return
+		}
+		fmt.Fprintf(diag, "Pruned %d unreachable rooms and %d tunnels\n", rooms, tunnels)
+	}
+
+	fmt.Fprintf(diag, "Farm: %d ants, start=%s, end=%s\n", farm.Ants, farm.Start, farm.End)
+	stats := farm.Stats()
+	fmt.Fprintf(diag, "Rooms: %d, tunnels: %d, end room degree: %d\n", stats.Rooms, stats.Tunnels, stats.EndDegree)
+	fmt.Fprintf(diag, "Start room has %d neighbors: %v\n", stats.StartDegree, farm.Rooms[farm.Start].Links)
+
+	prog.enter("path-finding")
+	var original *Farm
+	if opts.selfCheck {
+		original = farm.Clone()
 	}
 
-	// Use the best set of paths
 	var finalPaths [][]string
-	if len(nonOverlapPaths) > len(bestPaths) {
-		finalPaths = nonOverlapPaths
+	if opts.runs > 0 {
+		fmt.Fprintf(diag, "\n=== Strategy %s, %d runs ===\n", opts.algo, opts.runs)
+		var report runsReport
+		finalPaths, report = bestOfRuns(farm, strategy, opts.runs, opts.seed)
+		report.write(os.Stderr, opts.runs)
+		fmt.Fprintf(diag, "Best run found %d paths:\n", len(finalPaths))
+		writePaths(diag, finalPaths)
+	} else if opts.algo == "auto" && opts.verbose {
+		finalPaths = comparePaths(diag, farm, assign)
 	} else {
-		finalPaths = bestPaths
+		fmt.Fprintf(diag, "\n=== Strategy %s ===\n", opts.algo)
+		finalPaths = strategy(farm)
+		if flowTrace != nil && flowTrace.err != nil {
+			reportError(flowTrace.err)
+			return
+		}
+		fmt.Fprintf(diag, "Found %d paths:\n", len(finalPaths))
+		writePaths(diag, finalPaths)
+	}
+	if original != nil && !sameFarm(farm, original) {
+		fmt.Fprintln(os.Stderr, "Internal error: self-check failed: the path search changed the farm")
+		os.Exit(exitInternal)
+	}
+	if n := frontierFallbacks.Load(); n > 0 {
+		fmt.Fprintf(diag, "Over --max-frontier %d: %d searches used iterative deepening\n", maxFrontier, n)
 	}
+	finalPaths, dropped := disjointPaths(finalPaths)
+	for _, p := range dropped {
+		fmt.Fprintf(diag, "Dropped path %v: it shares rooms with a shorter path\n", displayPath(p))
+	}
+	if opts.smooth {
+		finalPaths = smoothPaths(farm, finalPaths)
+		fmt.Fprintln(diag, "Smoothed paths:")
+		writePaths(diag, finalPaths)
+	}
+	prog.paths.Store(int64(len(finalPaths)))
 
 	if len(finalPaths) == 0 {
-		fmt.Println("No valid paths found!")
+		reportError(errNoPath)
+		return
+	}
+	if opts.subgraph != "" {
+		sub := solutionSubgraph(farm, finalPaths)
+		err := writeFile(opts.subgraph, sub, func(w io.Writer, f *Farm) error {
+			_, err := io.WriteString(w, f.String())
+			return err
+		})
+		if err != nil {
+			reportError(err)
+			return
+		}
+		stats := sub.Stats()
+		fmt.Fprintf(diag, "Solution subgraph: %d rooms, %d tunnels written to %s\n", stats.Rooms, stats.Tunnels, opts.subgraph)
+	}
+
+	if opts.followPath > len(finalPaths) {
+		reportErrorf("--follow-path %d, but the solution has %d paths", opts.followPath, len(finalPaths))
+		return
+	}
+	if opts.turns > 0 {
+		fmt.Printf("%d ants can reach the end within %d turns\n", antsWithinTurns(finalPaths, opts.turns, opts.maxPerPath), opts.turns)
 		return
 	}
 
 	// Run simulation
-	fmt.Println("\n=== Simulation ===")
-	antDistribution := distributeAnts(farm.Ants, finalPaths)
+	fmt.Fprintln(diag, "\n=== Simulation ===")
+	prog.enter("distribution")
+	if err := checkCapacity(farm.Ants, finalPaths, opts.maxPerPath); err != nil {
+		reportError(err)
+		return
+	}
+	var sim *Simulator
+	if opts.scheduler == "reactive" {
+		sim = newReactiveSimulator(finalPaths, farm.Ants, opts.maxPerPath)
+	} else {
+		sim = newSimulator(finalPaths, assign(farm.Ants, finalPaths, opts.maxPerPath))
+	}
+	if opts.chaos > 0 {
+		sim.enableChaos(opts.chaos, opts.seed)
+	}
+
+	// The self-check needs the whole schedule before anything is printed
+	next := sim.Step
+	if len(farm.Spawns) > 0 {
+		next = spawnSteps(sim, farm.Spawns)
+	}
+	if opts.compact {
+		prog.enter("compaction")
+		turns := collectTurns(next)
+		compacted := compactTurns(farm, turns)
+		fmt.Fprintf(os.Stderr, "Compaction: %d turns saved (%d instead of %d)\n", len(turns)-len(compacted), len(compacted), len(turns))
+		next = replayTurns(compacted)
+	}
+	if opts.selfCheck {
+		prog.enter("self-check")
+		turns := collectTurns(next)
+		sol := Solution{Farm: farm, Paths: finalPaths, Distribution: sim.Distribution(), Turns: turns}
+		if err := checkSolution(withoutSpawns(sol)); err != nil {
+			fmt.Fprintln(os.Stderr, "Internal error: self-check failed:", err)
+			os.Exit(exitInternal)
+		}
+		next = replayTurns(turns)
+	}
 
-	result := simulateAnts(finalPaths, antDistribution)
-	fmt.Print(result)
+	// Report a closed output as a write error instead of being killed
+	signal.Ignore(syscall.SIGPIPE)
+	prog.enter("simulation")
+	var observers []func(Turn)
+	var collector *statsCollector
+	if opts.stats {
+		collector = newStatsCollector(farm, finalPaths)
+		observers = append(observers, collector.observe)
+	}
+	var replay *replayEncoder
+	if opts.replay != "" {
+		replay = newReplayEncoder(finalPaths)
+		observers = append(observers, replay.observe)
+	}
+	var arrivalFile *arrivalLog
+	if opts.arrivals != "" {
+		if arrivalFile, err = newArrivalLog(opts.arrivals); err != nil {
+			reportError(err)
+			return
+		}
+		observers = append(observers, arrivals(farm.End, arrivalFile.arrived))
+	}
+	moves := 0
+	if opts.countMoves {
+		observers = append(observers, func(turn Turn) { moves += len(turn) })
+	}
+	observe := func(turn Turn) {
+		for _, o := range observers {
+			o(turn)
+		}
+	}
+
+	output := io.Writer(os.Stdout)
+	var outFile *os.File
+	var tee *teeWriter
+	if opts.out != "" {
+		if outFile, err = os.Create(opts.out); err != nil {
+			reportError(err)
+			return
+		}
+		output = outFile
+		if opts.tee {
+			tee = &teeWriter{file: outFile, stdout: os.Stdout}
+			output = tee
+		}
+	}
+	write := writeTurn
+	if opts.groupBy == "path" {
+		write = groupedByPath(opts.pathLabels)
+	}
+	if followAnt > 0 {
+		write = following(write, func(m Move) bool { return m.Ant == followAnt })
+	} else if opts.followPath > 0 {
+		write = following(write, func(m Move) bool { return m.Path == opts.followPath-1 })
+	}
+	switch {
+	case opts.count || opts.countMoves:
+		err = streamAnts(io.Discard, func(io.Writer, Turn) error { return nil }, next, prog, observe)
+		if err == nil && opts.countMoves {
+			_, err = fmt.Fprintln(output, prog.turns.Load(), moves)
+		} else if err == nil {
+			_, err = fmt.Fprintln(output, prog.turns.Load())
+		}
+	default:
+		// Checked with the other options
+		enc, _ := lookupEncoder(opts.format, EncoderOptions{Moves: write, Strict: opts.strict, Input: input})
+		err = enc.Begin(output, farm)
+		if err == nil {
+			err = streamAnts(output, enc.Turn, next, prog, observe)
+		}
+		if err == nil {
+			err = enc.End(output, Solution{Farm: farm, Paths: finalPaths, Distribution: sim.Distribution()})
+		}
+	}
+	if outFile != nil {
+		if closeErr := outFile.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if arrivalFile != nil {
+		if closeErr := arrivalFile.close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err == nil && tee != nil {
+		err = tee.stdoutErr
+	}
+	if err == nil && replay != nil {
+		err = writeReplayFile(opts.replay, replay)
+	}
+	if err == nil && opts.chart != "" {
+		err = writeChart(opts.chart, finalPaths, sim.Distribution())
+	}
+	if errors.Is(err, errInterrupted) {
+		fmt.Fprintln(os.Stderr, prog.report())
+		os.Exit(exitInterrupted)
+	}
+	if err != nil && !errors.Is(err, syscall.EPIPE) {
+		writeError(os.Stderr, err)
+		os.Exit(1)
+	}
+	prog.finish()
+
+	if len(farm.Spawns) > 0 {
+		fmt.Fprintf(os.Stderr, "With ##spawn: %d turns for %d ants, %d of them spawned later\n",
+			prog.turns.Load(), farm.Ants+farm.spawnedAnts(), farm.spawnedAnts())
+	}
+	if opts.stats {
+		printStats(os.Stderr, prog, collector)
+	}
+	if opts.memStats {
+		printMemStats(os.Stderr, prog)
+	}
 }
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestPathLess(t *testing.T) {
+	tests := []struct {
+		a, b []string
+		want bool
+	}{
+		{[]string{"s", "e"}, []string{"s", "a", "e"}, true},
+		{[]string{"s", "a", "e"}, []string{"s", "e"}, false},
+		{[]string{"s", "a", "e"}, []string{"s", "b", "e"}, true},
+		{[]string{"s", "b", "e"}, []string{"s", "a", "e"}, false},
+		{[]string{"s", "a", "e"}, []string{"s", "a", "e"}, false},
+	}
+	for _, tt := range tests {
+		if got := pathLess(tt.a, tt.b); got != tt.want {
+			t.Errorf("pathLess(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSelectBestPathsOrder(t *testing.T) {
+	paths := [][]string{
+		{"s", "b", "e"},
+		{"s", "x", "y", "e"},
+		{"s", "a", "e"},
+		{"s", "a", "b", "e"},
+		{"s", "c", "d", "e"},
+	}
+	want := [][]string{{"s", "a", "e"}, {"s", "b", "e"}, {"s", "c", "d", "e"}, {"s", "x", "y", "e"}}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		shuffled := append([][]string(nil), paths...)
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		if got := selectBestPaths(nil, shuffled); !reflect.DeepEqual(got, want) {
+			t.Fatalf("from %v: got %v, want %v", shuffled, got, want)
+		}
+	}
+}
+
+func TestStartNeighbourOrder(t *testing.T) {
+	// c has the fewest tunnels; a and b tie and are taken by name
+	for _, tunnels := range []string{"s-b\ns-a\ns-c\n", "s-c\ns-a\ns-b\n"} {
+		f, err := parseInput(writeMap(t, "3\n##start\ns 0 0\na 1 0\nb 1 1\nc 1 2\nx 2 0\n##end\ne 3 0\n"+
+			tunnels+"a-e\nb-e\nc-e\na-x\nb-x\n"), ParseOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := [][]string{{"s", "c", "e"}, {"s", "a", "e"}, {"s", "b", "e"}}
+		if got := findNonOverlappingPaths(f); !reflect.DeepEqual(got, want) {
+			t.Errorf("tunnels %q: got %v, want %v", tunnels, got, want)
+		}
+	}
+}
+
+func TestDisjointPaths(t *testing.T) {
+	paths := [][]string{{"s", "x", "y", "e"}, {"s", "y", "x", "e"}, {"s", "e"}, {"s", "e"}}
+	kept, dropped := disjointPaths(paths)
+	want := [][]string{{"s", "x", "y", "e"}, {"s", "e"}}
+	if !reflect.DeepEqual(kept, want) || len(dropped) != 2 {
+		t.Errorf("kept %v, dropped %v; want to keep %v", kept, dropped, want)
+	}
+}
+
+// Every turn moves an ant, whatever the strategy and scheduler, even when
+// ants wait on the way (--chaos). cross and share made the paths overlap,
+// which used to leave ants stuck behind a used tunnel.
+func TestNoEmptyTurns(t *testing.T) {
+	for _, name := range []string{"cross", "share", "ex1", "ex2", "ex3", "zones", "dash"} {
+		f := loadTestMap(t, name)
+		for _, algo := range strategyNames() {
+			strategy, _ := lookupStrategy(algo)
+			paths, _, err := solveQuietly(f, strategy)
+			if err != nil {
+				t.Fatalf("%s, %s: %v", name, algo, err)
+			}
+			for _, reactive := range []bool{false, true} {
+				sim := newSimulator(paths, distributeAnts(f.Ants, paths, 0))
+				if reactive {
+					sim = newReactiveSimulator(paths, f.Ants, 0)
+				}
+				sim.enableChaos(0.4, 7)
+				turns := collectTurns(sim.Step)
+				for i, turn := range turns {
+					if len(turn) == 0 {
+						t.Errorf("%s, %s, reactive %v: turn %d is empty", name, algo, reactive, i+1)
+					}
+				}
+				sol := Solution{Farm: f, Paths: paths, Distribution: sim.Distribution(), Turns: turns}
+				if err := checkSolution(sol); err != nil {
+					t.Errorf("%s, %s, reactive %v: %v", name, algo, reactive, err)
+				}
+			}
+		}
+	}
+}
+
+// One ant leaves the start per path and per turn until the path has sent
+// all its ants. dup repeats the tunnel to its first room and share has
+// two paths through the same neighbour of the start.
+func TestOneDeparturePerPath(t *testing.T) {
+	for _, name := range []string{"dup", "share", "cross", "ex1", "ex2", "zones"} {
+		f := loadTestMap(t, name)
+		for _, algo := range strategyNames() {
+			strategy, _ := lookupStrategy(algo)
+			paths, turns, err := solveQuietly(f, strategy)
+			if err != nil {
+				t.Fatalf("%s, %s: %v", name, algo, err)
+			}
+			if want := predictTurns(paths, f.Ants, 0); turns != want {
+				t.Errorf("%s, %s: %d turns, %d predicted", name, algo, turns, want)
+			}
+			first := make(map[string]bool)
+			for _, p := range paths {
+				if first[p[1]] {
+					t.Errorf("%s, %s: two paths start with %s", name, algo, p[1])
+				}
+				first[p[1]] = true
+			}
+			dist := distributeAnts(f.Ants, paths, 0)
+			left := make(map[int]bool)
+			for i, turn := range simulateAnts(paths, dist) {
+				departures := make(map[int]int)
+				for _, m := range turn {
+					if !left[m.Ant] {
+						left[m.Ant] = true
+						departures[m.Path]++
+					}
+				}
+				for p, ants := range dist {
+					want := 0
+					if i < len(ants) {
+						want = 1
+					}
+					if departures[p] != want {
+						t.Errorf("%s, %s, turn %d: %d departures on path %d, want %d", name, algo, i+1, departures[p], p+1, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+var allocTurn = Turn{{Ant: 1, Room: "abc"}, {Ant: 200, Room: "room_42"}, {Ant: 37, Room: "end", Path: 1}}
+
+func TestTurnWriterAllocs(t *testing.T) {
+	w := bufio.NewWriter(io.Discard)
+	for _, tt := range []struct {
+		name  string
+		write turnWriter
+	}{
+		{"writeTurn", writeTurn},
+		{"groupedByPath", groupedByPath(false)},
+		{"groupedByPath with labels", groupedByPath(true)},
+	} {
+		if n := testing.AllocsPerRun(1000, func() { tt.write(w, allocTurn) }); n > 1 {
+			t.Errorf("%s: %v allocations per turn, want at most 1", tt.name, n)
+		}
+	}
+}
+
+func BenchmarkWriteTurn(b *testing.B) {
+	w := bufio.NewWriter(io.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		writeTurn(w, allocTurn)
+	}
+}
+
+func TestEvenAssignment(t *testing.T) {
+	// Balanced, all four ants take the direct tunnel; even, two of them
+	// take the path of five tunnels
+	f, paths := chainFarm([]int{1, 5}, 4)
+	balanced := simulateAnts(paths, distributeAnts(f.Ants, paths, 0))
+	even := simulateAnts(paths, distributeEvenly(f.Ants, paths, 0))
+	if len(balanced) != 4 || len(even) != 6 {
+		t.Errorf("%d turns balanced and %d even, want 4 and 6", len(balanced), len(even))
+	}
+
+	rng := rand.New(rand.NewSource(5))
+	for i := 0; i < 200; i++ {
+		lengths := make([]int, 1+rng.Intn(5))
+		for j := range lengths {
+			lengths[j] = 1 + rng.Intn(8)
+		}
+		f, paths := chainFarm(lengths, 1+rng.Intn(40))
+		dist := distributeEvenly(f.Ants, paths, 0)
+		fewest, most := f.Ants, 0
+		for _, ants := range dist {
+			if len(ants) < fewest {
+				fewest = len(ants)
+			}
+			if len(ants) > most {
+				most = len(ants)
+			}
+		}
+		if most-fewest > 1 {
+			t.Errorf("lengths %v, %d ants: %d to %d ants per path", lengths, f.Ants, fewest, most)
+		}
+		even := simulateAnts(paths, dist)
+		balanced := simulateAnts(paths, distributeAnts(f.Ants, paths, 0))
+		if len(even) < len(balanced) {
+			t.Errorf("lengths %v, %d ants: %d turns even, fewer than %d balanced", lengths, f.Ants, len(even), len(balanced))
+		}
+		sol := Solution{Farm: f, Paths: paths, Distribution: dist, Turns: even}
+		if err := checkSolution(sol); err != nil {
+			t.Errorf("lengths %v, %d ants: %v", lengths, f.Ants, err)
+		}
+	}
+}
+
+func TestZoneCommands(t *testing.T) {
+	rooms := "##start\ns 0 0\na 1 0\n##end\ne 2 0\ns-a\na-e\n"
+	tests := []struct {
+		command string
+		strict  bool
+		zones   int
+		code    string
+	}{
+		{"##zone z a", false, 1, ""},
+		{"##zone z", false, 0, codeZoneLine},
+		{"##zonefoo", false, 0, ""},
+		{"##zone z a", true, 0, ""},
+	}
+	for _, tt := range tests {
+		f, err := parseInput(writeMap(t, "1\n"+tt.command+"\n"+rooms), ParseOptions{Strict: tt.strict})
+		switch {
+		case errorCode(err) != tt.code || (tt.code == "" && err != nil):
+			t.Errorf("%q, strict %v: got %v, want code %q", tt.command, tt.strict, err, tt.code)
+		case err == nil && len(f.Zones) != tt.zones:
+			t.Errorf("%q, strict %v: %d zones, want %d", tt.command, tt.strict, len(f.Zones), tt.zones)
+		}
+	}
+}
+
+// With one ant a path, the second ant needs the longer path, although both
+// ants take the short one faster without a cap
+func TestMaxPerPathForcesSecondPath(t *testing.T) {
+	f, err := parseInput(writeMap(t, "2\n##start\ns 0 0\na 1 0\nb 1 1\nc 2 1\nd 3 1\n##end\ne 4 0\n"+
+		"s-a\na-e\ns-b\nb-c\nc-d\nd-e\n"), ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(previous int) { maxAntsPerPath = previous }(maxAntsPerPath)
+	if paths := flowPaths(f); len(paths) != 1 {
+		t.Errorf("flow without a cap: %d paths, want 1", len(paths))
+	}
+	for _, name := range []string{"flow", "auto"} {
+		strategy, err := lookupStrategy(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		maxAntsPerPath = 1
+		paths, _ := disjointPaths(strategy(f))
+		if err := checkCapacity(f.Ants, paths, 1); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	paths := [][]string{{"s", "a", "e"}, {"s", "b", "c", "d", "e"}}
+	if n := antsWithinTurns(paths, 10, 1); n != 2 {
+		t.Errorf("%d ants within 10 turns at 1 per path, want 2", n)
+	}
+}
+
+func TestSpawnCommands(t *testing.T) {
+	rooms := "##start\ns 0 0\na 1 0\n##end\ne 2 0\ns-a\na-e\n"
+	tests := []struct {
+		command string
+		strict  bool
+		spawns  int
+		code    string
+	}{
+		{"##spawn 3 ants at turn 2", false, 1, ""},
+		{"##spawn 3 ants", false, 0, codeSpawnLine},
+		{"##spawn 3 ants at turn 2", true, 0, codeSpawnStrict},
+		{"##spawned", true, 0, ""},
+	}
+	for _, tt := range tests {
+		f, err := parseInput(writeMap(t, "1\n"+tt.command+"\n"+rooms), ParseOptions{Strict: tt.strict})
+		switch {
+		case errorCode(err) != tt.code || (tt.code == "" && err != nil):
+			t.Errorf("%q, strict %v: got %v, want code %q", tt.command, tt.strict, err, tt.code)
+		case err == nil && len(f.Spawns) != tt.spawns:
+			t.Errorf("%q, strict %v: %d spawns, want %d", tt.command, tt.strict, len(f.Spawns), tt.spawns)
+		}
+	}
+}
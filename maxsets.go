@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ----- Enumerating the largest sets of disjoint paths -----
+// A farm usually has many sets of disjoint paths as large as the maximum
+// flow allows. MaxDisjointSets lists them for analysis tools, shortest
+// paths first, from the same simple paths as the exact solver, so it is
+// meant for small maps. Iteration stops after limit sets (0: no limit) or
+// as soon as yield returns false, in the manner of an iter.Seq.
+func MaxDisjointSets(f *Farm, limit int, yield func(set [][]string) bool) error {
+	paths, err := allSimplePaths(f, maxExactPaths)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no path from %s to %s", f.Start, f.End)
+	}
+	sort.SliceStable(paths, func(i, j int) bool { return pathLess(paths[i], paths[j]) })
+	size := minCut(f).size()
+
+	var current [][]string
+	used := make(map[string]bool)
+	found := 0
+	stopped := false
+
+	var search func(from int)
+	search = func(from int) {
+		if len(current) == size {
+			found++
+			stopped = !yield(append([][]string(nil), current...)) || found == limit
+			return
+		}
+		for i := from; i < len(paths) && !stopped; i++ {
+			if len(current)+len(paths)-i < size {
+				return
+			}
+			if pathUsesRooms(paths[i], used) {
+				continue
+			}
+			setRooms(paths[i], used, true)
+			current = append(current, paths[i])
+			search(i + 1)
+			current = current[:len(current)-1]
+			setRooms(paths[i], used, false)
+		}
+	}
+	search(0)
+	return nil
+}
+
+// Largest sets listed by verify --max-sets, and how many of them need the
+// fewest turns
+type largestSets struct {
+	size    int  // paths in each set
+	count   int  // sets listed
+	optimal int  // of them, sets needing the given number of turns
+	capped  bool // there may be more than count
+	chosen  bool // the solver's paths carry ants like one of the sets
+}
+
+func countLargestSets(f *Farm, limit, turns int, solver [][]string) (largestSets, error) {
+	var sets largestSets
+	key := pathSetKey(f.Ants, solver)
+	err := MaxDisjointSets(f, limit, func(set [][]string) bool {
+		sets.size = len(set)
+		sets.count++
+		if predictTurns(set, f.Ants, 0) == turns {
+			sets.optimal++
+		}
+		if pathSetKey(f.Ants, set) == key {
+			sets.chosen = true
+		}
+		return true
+	})
+	sets.capped = limit > 0 && sets.count == limit
+	return sets, err
+}
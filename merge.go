@@ -0,0 +1,169 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// ----- merge: build one farm out of two maps -----
+// lem-in merge [--bridge a=b,...] [--join a=b,...] [--prefix p] first.txt second.txt
+// The merged farm starts in the start room of the first map and ends in
+// the end room of the second map; the other two become ordinary rooms. In
+// every a=b pair, a is a room of the first map and b a room of the second:
+// --bridge adds a tunnel between them, --join makes them one room (the
+// first map's). Rooms and zones of the second map whose names are taken
+// in the first one get the prefix, and its rooms are moved to the right
+// of the first map so that no two rooms share coordinates.
+func runMerge(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	bridges := fs.String("bridge", "", "tunnels to add between the maps, e.g. a1=b1,a2=b2")
+	joins := fs.String("join", "", "rooms of the two maps to make one, e.g. a=b")
+	prefix := fs.String("prefix", "b_", "prefix for names of the second map that are taken in the first")
+	args, _ = parseFlags(fs, args)
+	if len(args) != 2 {
+		fmt.Println("Usage: go run . merge [--bridge a=b,...] [--join a=b,...] [--prefix p] first.txt second.txt")
+		return
+	}
+	var farms [2]*Farm
+	for i, file := range args {
+		f, err := parseInput(file, ParseOptions{})
+		if err != nil {
+			reportErrorf("%s: %w", file, err)
+			return
+		}
+		farms[i] = f
+	}
+	bridgePairs, err := parsePairs("--bridge", *bridges, farms)
+	if err != nil {
+		reportError(err)
+		return
+	}
+	joinPairs, err := parsePairs("--join", *joins, farms)
+	if err != nil {
+		reportError(err)
+		return
+	}
+	merged, err := mergeFarms(farms[0], farms[1], bridgePairs, joinPairs, *prefix)
+	if err != nil {
+		reportError(err)
+		return
+	}
+	fmt.Print(merged)
+}
+
+// parsePairs reads "a=b,c=d" with a in the first farm and b in the second
+func parsePairs(flagName, s string, farms [2]*Farm) ([][2]string, error) {
+	var pairs [][2]string
+	if s == "" {
+		return nil, nil
+	}
+	for _, item := range strings.Split(nfc(s), ",") {
+		a, b, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s: expected room=room, got %q", flagName, item)
+		}
+		if farms[0].Rooms[a] == nil {
+			return nil, fmt.Errorf("%s: no room %q in the first map", flagName, a)
+		}
+		if farms[1].Rooms[b] == nil {
+			return nil, fmt.Errorf("%s: no room %q in the second map", flagName, b)
+		}
+		pairs = append(pairs, [2]string{a, b})
+	}
+	return pairs, nil
+}
+
+func mergeFarms(a, b *Farm, bridges, joins [][2]string, prefix string) (*Farm, error) {
+	out := newFarm()
+	out.Ants = a.Ants
+	maxX := 0
+	for _, name := range a.Names {
+		room := a.Rooms[name]
+		out.addRoom(name, room.X, room.Y)
+		if room.X > maxX {
+			maxX = room.X
+		}
+	}
+	minX := 0
+	for i, name := range b.Names {
+		if x := b.Rooms[name].X; i == 0 || x < minX {
+			minX = x
+		}
+	}
+
+	// Name of every room of the second map in the merged farm
+	renamed := make(map[string]string, len(b.Names))
+	for _, pair := range joins {
+		if _, done := renamed[pair[1]]; done {
+			return nil, fmt.Errorf("--join: room %q of the second map is joined twice", pair[1])
+		}
+		renamed[pair[1]] = pair[0]
+	}
+	for _, name := range b.Names {
+		if _, joined := renamed[name]; joined {
+			continue
+		}
+		newName := name
+		if out.Rooms[newName] != nil {
+			newName = prefix + name
+		}
+		if out.Rooms[newName] != nil {
+			return nil, fmt.Errorf("room %q of the second map is taken even with the prefix %q", name, prefix)
+		}
+		room := b.Rooms[name]
+		renamed[name] = out.addRoom(newName, room.X-minX+maxX+1, room.Y).Name
+	}
+	out.Start, out.End = a.Start, renamed[b.End]
+	if out.Start == out.End {
+		return nil, fmt.Errorf("the start room of the first map is joined to the end room of the second")
+	}
+
+	link := func(x, y string) {
+		if x == y {
+			return
+		}
+		for _, l := range out.Rooms[x].Links {
+			if l == y {
+				return
+			}
+		}
+		out.addTunnel(out.Rooms[x], out.Rooms[y])
+	}
+	for _, t := range a.tunnelList() {
+		link(t[0], t[1])
+	}
+	for _, t := range b.tunnelList() {
+		link(renamed[t[0]], renamed[t[1]])
+	}
+	for _, pair := range bridges {
+		link(pair[0], renamed[pair[1]])
+	}
+
+	out.Zones = append(out.Zones, a.Zones...)
+	zoned := make(map[string]bool)
+	for _, zone := range a.Zones {
+		zoned[zone.Name] = true
+		for _, name := range zone.Rooms {
+			out.Rooms[name].Zone = zone.Name
+		}
+	}
+	for _, zone := range b.Zones {
+		z := Zone{Name: zone.Name}
+		if zoned[z.Name] {
+			z.Name = prefix + z.Name
+		}
+		for _, name := range zone.Rooms {
+			room := out.Rooms[renamed[name]]
+			if room.Zone != "" {
+				continue // a joined room stays in the first map's zone
+			}
+			room.Zone = z.Name
+			z.Rooms = append(z.Rooms, room.Name)
+		}
+		if len(z.Rooms) > 0 {
+			out.Zones = append(out.Zones, z)
+		}
+	}
+	return out, nil
+}
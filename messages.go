@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ----- Translated error messages -----
+// Errors about the map carry a code that stays the same in every language,
+// so that scripts and graders can match on it; the text comes from the
+// catalog of the current language. English is the default, French is
+// picked from LC_ALL, LC_MESSAGES or LANG, or with --lang fr.
+const (
+	codeAnts            = "E101"
+	codeZoneLine        = "E102"
+	codeRoomLine        = "E103"
+	codeDuplicateRoom   = "E104"
+	codeCoordinates     = "E105"
+	codeDuplicateCoords = "E106"
+	codeTooManyRooms    = "E107"
+	codeTwoStarts       = "E108"
+	codeTwoEnds         = "E109"
+	codeUnknownRoom     = "E110"
+	codeTooManyTunnels  = "E111"
+	codeLineFormat      = "E112"
+	codeLongLine        = "E113"
+	codeNoStartEnd      = "E114"
+	codeZoneRoom        = "E115"
+	codeTwoZones        = "E116"
+	codeNameUTF8        = "E117"
+	codeNameASCII       = "E118"
+	codeNameUnprintable = "E120"
+	codeQuotedName      = "E121"
+	codeAmbiguousTunnel = "E122"
+	codeTunnelLine      = "E123"
+	codeSpawnLine       = "E124"
+	codeSelfTunnel      = "E125"
+	codeSpawnStrict     = "E126"
+	codeNoPath          = "E201"
+	codeNoTunnels       = "E202"
+	codeEndUnreachable  = "E203"
+)
+
+var catalogs = map[string]map[string]string{
+	"en": {
+		"Error":             "Error",
+		codeAnts:            "invalid number of ants: %q",
+		codeZoneLine:        "invalid zone definition: %q",
+		codeRoomLine:        "invalid room definition: %q",
+		codeDuplicateRoom:   "duplicate room name: %q",
+		codeCoordinates:     "invalid coordinates for room %q",
+		codeDuplicateCoords: "duplicate coordinates (%d,%d)",
+		codeTooManyRooms:    "more than %d rooms",
+		codeTwoStarts:       "more than one start room defined",
+		codeTwoEnds:         "more than one end room defined",
+		codeUnknownRoom:     "tunnel references unknown room(s): %q",
+		codeTooManyTunnels:  "more than %d tunnels",
+		codeLineFormat:      "invalid line format: %q",
+		codeLongLine:        "a line is longer than %d bytes",
+		codeNoStartEnd:      "missing start or end room",
+		codeZoneRoom:        "zone %q references unknown room %q",
+		codeTwoZones:        "room %q is in zones %q and %q",
+		codeNameUTF8:        "room name %q is not valid UTF-8",
+		codeNameASCII:       "room name %q must be plain ASCII in strict mode",
+		codeNameUnprintable: "room name %q contains unprintable characters",
+		codeQuotedName:      "invalid quoted room name: %s",
+		codeAmbiguousTunnel: "ambiguous tunnel line %q: quote room names containing '-'",
+		codeTunnelLine:      "invalid tunnel line: %q",
+		codeSpawnLine:       "invalid spawn definition: %q, expected ##spawn N ants at turn T",
+		codeSelfTunnel:      "tunnel from room %q to itself",
+		codeSpawnStrict:     "##spawn is an extension, not allowed in strict mode: %q",
+		codeNoPath:          "no path from start to end",
+		codeNoTunnels:       "no path from start to end: room %q has no tunnels",
+		codeEndUnreachable:  "no path from start to end: end room %q cannot be reached",
+	},
+	"fr": {
+		"Error":             "Erreur",
+		codeAnts:            "nombre de fourmis invalide : %q",
+		codeZoneLine:        "définition de zone invalide : %q",
+		codeRoomLine:        "définition de salle invalide : %q",
+		codeDuplicateRoom:   "nom de salle en double : %q",
+		codeCoordinates:     "coordonnées invalides pour la salle %q",
+		codeDuplicateCoords: "coordonnées en double (%d,%d)",
+		codeTooManyRooms:    "plus de %d salles",
+		codeTwoStarts:       "plusieurs salles de départ sont définies",
+		codeTwoEnds:         "plusieurs salles d'arrivée sont définies",
+		codeUnknownRoom:     "le tunnel relie une ou des salles inconnues : %q",
+		codeTooManyTunnels:  "plus de %d tunnels",
+		codeLineFormat:      "format de ligne invalide : %q",
+		codeLongLine:        "une ligne dépasse %d octets",
+		codeNoStartEnd:      "salle de départ ou d'arrivée manquante",
+		codeZoneRoom:        "la zone %q contient la salle inconnue %q",
+		codeTwoZones:        "la salle %q est dans les zones %q et %q",
+		codeNameUTF8:        "le nom de salle %q n'est pas de l'UTF-8 valide",
+		codeNameASCII:       "le nom de salle %q doit être en ASCII simple en mode strict",
+		codeNameUnprintable: "le nom de salle %q contient des caractères non imprimables",
+		codeQuotedName:      "nom de salle entre guillemets invalide : %s",
+		codeAmbiguousTunnel: "ligne de tunnel ambiguë %q : mettez entre guillemets les noms contenant '-'",
+		codeTunnelLine:      "ligne de tunnel invalide : %q",
+		codeSpawnLine:       "définition d'apparition invalide : %q, attendu ##spawn N ants at turn T",
+		codeSelfTunnel:      "tunnel de la salle %q vers elle-même",
+		codeSpawnStrict:     "##spawn est une extension, refusée en mode strict : %q",
+		codeNoPath:          "aucun chemin du départ à l'arrivée",
+		codeNoTunnels:       "aucun chemin du départ à l'arrivée : la salle %q n'a aucun tunnel",
+		codeEndUnreachable:  "aucun chemin du départ à l'arrivée : la salle d'arrivée %q est inaccessible",
+	},
+}
+
+// language is the catalog in use, set from the environment when main
+// starts and from --lang afterwards
+var language = languageFromEnv()
+
+func languageFromEnv() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			if strings.HasPrefix(value, "fr") {
+				return "fr"
+			}
+			return "en"
+		}
+	}
+	return "en"
+}
+
+func message(key string) string {
+	if text, ok := catalogs[language][key]; ok {
+		return text
+	}
+	return catalogs["en"][key]
+}
+
+// A codedError is rendered in the current language when it is printed,
+// not when it is made, so --lang also applies to errors kept around.
+type codedError struct {
+	code    string
+	args    []interface{}
+	wrapped error // for errors.Is, such as errNoPath
+}
+
+func (e *codedError) Error() string { return fmt.Sprintf(message(e.code), e.args...) }
+func (e *codedError) Unwrap() error { return e.wrapped }
+
+func codedErrorf(code string, args ...interface{}) error {
+	return &codedError{code: code, args: args}
+}
+
+// noPathError is a more precise errNoPath, which errors.Is still matches
+func noPathError(code string, args ...interface{}) error {
+	return &codedError{code: code, args: args, wrapped: errNoPath}
+}
+
+// errorCode returns the code of err, or "" when it has none
+func errorCode(err error) string {
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	return ""
+}
+
+// reportError prints err the way main reports errors, in the current
+// language and followed by its code when it has one
+func reportError(err error) {
+	writeError(os.Stdout, err)
+}
+
+// reportErrorf is reportError for a message made on the spot; %w keeps
+// the code of a wrapped error
+func reportErrorf(format string, args ...interface{}) {
+	reportError(fmt.Errorf(format, args...))
+}
+
+// writeError is reportError to w, for the errors that go to stderr
+func writeError(w io.Writer, err error) {
+	if code := errorCode(err); code != "" {
+		fmt.Fprintf(w, "%s: %v [%s]\n", message("Error"), err, code)
+		return
+	}
+	fmt.Fprintf(w, "%s: %v\n", message("Error"), err)
+}
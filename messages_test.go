@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Every map of testdata/invalid is named after the code it must fail with
+func TestInvalidMaps(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "invalid", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no maps in testdata/invalid")
+	}
+	for _, file := range files {
+		want, _, _ := strings.Cut(filepath.Base(file), "-")
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			_, err := parseInput(file, ParseOptions{})
+			if got := errorCode(err); got != want {
+				t.Errorf("got %v (code %q), want code %s", err, got, want)
+			}
+			if want == codeNoTunnels && !errors.Is(err, errNoPath) {
+				t.Errorf("%v is not a no-path error", err)
+			}
+		})
+	}
+}
+
+// Every code has a message in every language
+func TestMessageCatalogs(t *testing.T) {
+	for lang, catalog := range catalogs {
+		for code := range catalogs["en"] {
+			if catalog[code] == "" {
+				t.Errorf("%s: no message for %s", lang, code)
+			}
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ----- minimize: shrink a map while the solver still misbehaves -----
+// lem-in minimize map.txt --while 'turns>20' [--algo name] > small.txt
+// Rooms (never start or end) and then tunnels are removed in halves, then
+// quarters and so on down to one at a time, keeping every removal after
+// which the condition still holds, until nothing more can go. The
+// condition is one or more tests joined by &&:
+//
+//	turns, paths, rooms, tunnels  compared with <, <=, >, >=, == or != to a number
+//	error                         the solver finds no path
+//	panic                         the solver panics
+func runMinimize(args []string) {
+	fs := flag.NewFlagSet("minimize", flag.ExitOnError)
+	while := fs.String("while", "", "condition on the solved map that the result must keep, e.g. 'turns>20'")
+	algo := fs.String("algo", envDefault("LEMIN_ALGO", "auto"), "path-finding strategy")
+	args, _ = parseFlags(fs, args)
+	if len(args) != 1 || *while == "" {
+		fmt.Println("Usage: go run . minimize input.txt --while condition [--algo name]")
+		return
+	}
+	cond, err := parseCondition(*while)
+	if err != nil {
+		reportError(err)
+		return
+	}
+	strategy, err := lookupStrategy(*algo)
+	if err != nil {
+		reportError(err)
+		return
+	}
+	farm, err := parseInput(args[0], ParseOptions{})
+	if err != nil {
+		reportError(err)
+		return
+	}
+	m := &minimizer{cond: cond, strategy: strategy}
+	if !m.holds(farm) {
+		reportErrorf("%s does not hold for %s", *while, args[0])
+		return
+	}
+	small := m.minimize(farm)
+	before, after := farm.Stats(), small.Stats()
+	fmt.Fprintf(os.Stderr, "Removed %d rooms and %d tunnels in %d solver runs\n",
+		before.Rooms-after.Rooms, before.Tunnels-after.Tunnels, m.runs)
+	fmt.Print(small)
+}
+
+type minimizer struct {
+	cond     []condition
+	strategy Strategy
+	runs     int
+}
+
+// A condition is one test of --while; a nil compare stands for error or
+// panic, which test the outcome rather than a number.
+type condition struct {
+	metric  string
+	compare func(a, b int) bool
+	value   int
+}
+
+var comparisons = []struct {
+	op      string
+	compare func(a, b int) bool
+}{
+	// Two-character operators first, so that ">=" is not read as ">"
+	{"<=", func(a, b int) bool { return a <= b }},
+	{">=", func(a, b int) bool { return a >= b }},
+	{"==", func(a, b int) bool { return a == b }},
+	{"!=", func(a, b int) bool { return a != b }},
+	{"<", func(a, b int) bool { return a < b }},
+	{">", func(a, b int) bool { return a > b }},
+}
+
+func parseCondition(s string) ([]condition, error) {
+	var conds []condition
+	for _, test := range strings.Split(s, "&&") {
+		test = strings.TrimSpace(test)
+		if test == "error" || test == "panic" {
+			conds = append(conds, condition{metric: test})
+			continue
+		}
+		found := false
+		for _, c := range comparisons {
+			metric, value, ok := strings.Cut(test, c.op)
+			if !ok {
+				continue
+			}
+			metric = strings.TrimSpace(metric)
+			switch metric {
+			case "turns", "paths", "rooms", "tunnels":
+			default:
+				return nil, fmt.Errorf("unknown value %q in condition %q", metric, test)
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid number in condition %q", test)
+			}
+			conds = append(conds, condition{metric: metric, compare: c.compare, value: n})
+			found = true
+			break
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid condition %q", test)
+		}
+	}
+	return conds, nil
+}
+
+// holds solves f and tests every condition on the outcome
+func (m *minimizer) holds(f *Farm) bool {
+	m.runs++
+	var paths [][]string
+	var turns int
+	var err error
+	panicked := func() (p bool) {
+		defer func() {
+			if recover() != nil {
+				p = true
+			}
+		}()
+		paths, turns, err = solveQuietly(f, m.strategy)
+		return false
+	}()
+
+	stats := f.Stats()
+	values := map[string]int{"turns": turns, "paths": len(paths), "rooms": stats.Rooms, "tunnels": stats.Tunnels}
+	for _, c := range m.cond {
+		switch {
+		case c.metric == "panic":
+			if !panicked {
+				return false
+			}
+		case c.metric == "error":
+			if panicked || err == nil {
+				return false
+			}
+		case panicked || err != nil:
+			return false
+		case !c.compare(values[c.metric], c.value):
+			return false
+		}
+	}
+	return true
+}
+
+func (m *minimizer) minimize(f *Farm) *Farm {
+	for changed := true; changed; {
+		changed = false
+		var rooms []string
+		for _, name := range f.Names {
+			if name != f.Start && name != f.End {
+				rooms = append(rooms, name)
+			}
+		}
+		f, changed = m.removeChunks(f, len(rooms), func(drop func(i int) bool) *Farm {
+			dropped := make(map[string]bool)
+			for i, name := range rooms {
+				if drop(i) {
+					dropped[name] = true
+				}
+			}
+			return f.subFarm(func(name string) bool { return !dropped[name] }, nil)
+		})
+
+		tunnels := f.tunnelList()
+		var removedTunnels bool
+		f, removedTunnels = m.removeChunks(f, len(tunnels), func(drop func(i int) bool) *Farm {
+			dropped := make(map[[2]string]bool)
+			for i, t := range tunnels {
+				if drop(i) {
+					dropped[t] = true
+				}
+			}
+			return f.subFarm(func(string) bool { return true }, func(a, b string) bool {
+				return !dropped[[2]string{a, b}]
+			})
+		})
+		changed = changed || removedTunnels
+	}
+	return f
+}
+
+// removeChunks tries to drop n elements of f, numbered from 0, by chunks
+// of decreasing size. without builds the farm lacking the elements for
+// which drop is true; the numbering stays the one of the farm passed in.
+func (m *minimizer) removeChunks(f *Farm, n int, without func(drop func(i int) bool) *Farm) (*Farm, bool) {
+	removed := make([]bool, n)
+	changed := false
+	for chunk := (n + 1) / 2; chunk >= 1; chunk /= 2 {
+		for from := 0; from < n; from += chunk {
+			to := from + chunk
+			if to > n {
+				to = n
+			}
+			fresh := false // some element of the chunk is still there
+			for i := from; i < to; i++ {
+				fresh = fresh || !removed[i]
+			}
+			if !fresh {
+				continue
+			}
+			candidate := without(func(i int) bool { return removed[i] || (i >= from && i < to) })
+			if m.holds(candidate) {
+				for i := from; i < to; i++ {
+					removed[i] = true
+				}
+				f, changed = candidate, true
+			}
+		}
+	}
+	return f, changed
+}
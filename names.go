@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ----- Room name rules -----
+// Strict mode only accepts printable ASCII names. Lenient mode accepts any
+// printable UTF-8 name (accents, RTL scripts, ...). The parsers put names
+// in NFC first (see nfc), so a name typed with combining accents is the
+// same room as its composed spelling; the marks that remain, such as
+// Devanagari vowel signs or Hebrew points, are part of the name.
+func checkRoomName(name string, opts ParseOptions) error {
+	if !utf8.ValidString(name) {
+		return codedErrorf(codeNameUTF8, name)
+	}
+	for _, r := range name {
+		if opts.Strict && (r < '!' || r > '~') {
+			return codedErrorf(codeNameASCII, name)
+		}
+		if !unicode.IsPrint(r) {
+			return codedErrorf(codeNameUnprintable, name)
+		}
+	}
+	return nil
+}
+
+// ----- Room names containing dashes -----
+// A tunnel line "a-b-c" is ambiguous when room names contain '-'. Names can
+// be quoted, both in room and tunnel lines: "room-a" 1 2 and "room-a"-b.
+// Unquoted, lenient mode splits at the only dash that gives two known
+// rooms; strict mode requires the quotes.
+func unquoteName(s string) (string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return s, nil
+	}
+	if len(s) < 3 || !strings.HasSuffix(s, `"`) || strings.Count(s, `"`) != 2 {
+		return "", codedErrorf(codeQuotedName, s)
+	}
+	return s[1 : len(s)-1], nil
+}
+
+// quoteName is the inverse used when writing maps back as text
+func quoteName(name string) string {
+	if strings.Contains(name, "-") {
+		return `"` + name + `"`
+	}
+	return name
+}
+
+// splitTunnel reads the two room names of a tunnel line
+func splitTunnel(line string, f *Farm, opts ParseOptions) (string, string, error) {
+	if strings.Contains(line, `"`) {
+		return splitQuotedTunnel(line)
+	}
+	if strings.Count(line, "-") == 1 {
+		parts := strings.Split(line, "-")
+		return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), nil
+	}
+	if opts.Strict {
+		return "", "", codedErrorf(codeAmbiguousTunnel, line)
+	}
+	var a, b string
+	matches := 0
+	for i, c := range line {
+		if c != '-' {
+			continue
+		}
+		left, right := line[:i], line[i+1:]
+		if f.Rooms[left] != nil && f.Rooms[right] != nil {
+			a, b = left, right
+			matches++
+		}
+	}
+	switch matches {
+	case 0:
+		return "", "", codedErrorf(codeUnknownRoom, line)
+	case 1:
+		return a, b, nil
+	}
+	return "", "", codedErrorf(codeAmbiguousTunnel, line)
+}
+
+func splitQuotedTunnel(line string) (string, string, error) {
+	var names []string
+	rest := line
+	for len(names) < 2 {
+		var name string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				return "", "", codedErrorf(codeTunnelLine, line)
+			}
+			name, rest = rest[1:end+1], rest[end+2:]
+		} else if len(names) == 0 {
+			dash := strings.Index(rest, "-")
+			if dash < 0 {
+				return "", "", codedErrorf(codeTunnelLine, line)
+			}
+			name, rest = rest[:dash], rest[dash:]
+		} else {
+			name, rest = rest, ""
+		}
+		names = append(names, name)
+		if len(names) == 1 {
+			if !strings.HasPrefix(rest, "-") {
+				return "", "", codedErrorf(codeTunnelLine, line)
+			}
+			rest = rest[1:]
+		}
+	}
+	if rest != "" || strings.Contains(names[1], `"`) {
+		return "", "", codedErrorf(codeTunnelLine, line)
+	}
+	return names[0], names[1], nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Written with escapes: an editor could normalize the literal characters
+const (
+	cafeComposed   = "caf\u00e9"
+	cafeDecomposed = "cafe\u0301"
+	hindi          = "\u0939\u093f\u0902\u0926\u0940"             // the vowel signs stay
+	shalom         = "\u05e9\u05b8\u05c1\u05dc\u05d5\u05b9\u05dd" // points in canonical order
+	shalomShinDot  = "\u05e9\u05c1\u05b8\u05dc\u05d5\u05b9\u05dd" // the shin dot typed first
+	hangul         = "\ud55c"                                     // one syllable
+	hangulJamo     = "\u1112\u1161\u11ab"                         // its three letters
+)
+
+func TestNFC(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"ascii", "room_1", "room_1"},
+		{"combining accent", cafeDecomposed, cafeComposed},
+		{"already composed", cafeComposed, cafeComposed},
+		{"two accents", "o\u0302\u0301", "\u1ed1"},
+		{"marks reordered", "a\u0301\u0323", "\u1ea1\u0301"},
+		{"singleton", "\u212b", "\u00c5"},
+		{"hangul jamo", hangulJamo, hangul},
+		{"devanagari", hindi, hindi},
+		{"hebrew points", shalomShinDot, shalom},
+		{"invalid utf-8", "a\xffb", "a\xffb"},
+	}
+	for _, tt := range tests {
+		if got := nfc(tt.in); got != tt.want {
+			t.Errorf("%s: nfc(%+q) = %+q, want %+q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func writeMap(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "map.txt")
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestUnicodeRoomNames(t *testing.T) {
+	// Each tunnel spells its rooms differently from their room line
+	file := writeMap(t, "2\n"+
+		"##start\n"+cafeDecomposed+" 0 0\n"+
+		hindi+" 1 0\n"+
+		shalomShinDot+" 2 0\n"+
+		"##end\n"+hangulJamo+" 3 0\n"+
+		cafeComposed+"-"+hindi+"\n"+
+		hindi+"-"+shalom+"\n"+
+		shalom+"-"+hangul+"\n")
+	farm, err := parseInput(file, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(farm.Names) != 4 {
+		t.Errorf("%d rooms, want 4: %+q", len(farm.Names), farm.Names)
+	}
+	if farm.Start != cafeComposed || farm.End != hangul || farm.Rooms[shalom] == nil {
+		t.Errorf("rooms %+q, start %+q, end %+q", farm.Names, farm.Start, farm.End)
+	}
+	paths, _ := disjointPaths(autoPaths(farm))
+	if len(paths) != 1 || len(paths[0]) != 4 {
+		t.Errorf("paths %+q, want one through all 4 rooms", paths)
+	}
+
+	// The same name twice, once decomposed
+	file = writeMap(t, "1\n##start\n"+cafeComposed+" 0 0\n"+cafeDecomposed+" 1 0\n##end\ne 2 0\n"+cafeComposed+"-e\n")
+	if _, err := parseInput(file, ParseOptions{}); errorCode(err) != codeDuplicateRoom {
+		t.Errorf("duplicate decomposed name: got %v, want %s", err, codeDuplicateRoom)
+	}
+
+	file = writeMap(t, "1\n##start\n"+cafeComposed+" 0 0\n##end\ne 2 0\n"+cafeComposed+"-e\n")
+	if _, err := parseInput(file, ParseOptions{Strict: true}); errorCode(err) != codeNameASCII {
+		t.Errorf("strict mode: got %v, want %s", err, codeNameASCII)
+	}
+}
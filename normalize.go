@@ -0,0 +1,160 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"unicode/utf8"
+)
+
+//go:generate go run gennorm.go UnicodeData.txt CompositionExclusions.txt
+
+// ----- Unicode normalization of room names (NFC) -----
+// "é" can be written as one character or as "e" followed by a combining
+// accent; both spellings look the same but are different strings. Names
+// are put in Normalization Form C before they become keys, so that both
+// name the same room. The tables of normtables.go come from the Unicode
+// character database; Hangul syllables are composed arithmetically.
+type combiningRange struct {
+	lo, hi rune
+	class  uint8
+}
+
+var (
+	normOnce           sync.Once
+	fullDecompositions map[rune][]rune
+	compositions       map[[2]rune]rune
+)
+
+func loadNormTables() {
+	excluded := make(map[rune]bool, len(compositionExclusions))
+	for _, r := range compositionExclusions {
+		excluded[r] = true
+	}
+	var expand func(r rune) []rune
+	expand = func(r rune) []rune {
+		d, ok := canonicalDecompositions[r]
+		if !ok {
+			return []rune{r}
+		}
+		var runes []rune
+		for _, c := range d {
+			runes = append(runes, expand(c)...)
+		}
+		return runes
+	}
+	fullDecompositions = make(map[rune][]rune, len(canonicalDecompositions))
+	compositions = make(map[[2]rune]rune)
+	for r, d := range canonicalDecompositions {
+		fullDecompositions[r] = expand(r)
+		if len(d) == 2 && !excluded[r] && combiningClass(r) == 0 && combiningClass(d[0]) == 0 {
+			compositions[[2]rune{d[0], d[1]}] = r
+		}
+	}
+}
+
+// nfc returns s in Normalization Form C. Invalid UTF-8 is returned as it
+// is, for the name checks to report.
+func nfc(s string) string {
+	if isNFCQuick(s) || !utf8.ValidString(s) {
+		return s
+	}
+	normOnce.Do(loadNormTables)
+	var runes []rune
+	for _, r := range s {
+		runes = appendDecomposed(runes, r)
+	}
+	// Marks that follow the same character are put in order of class
+	for i := 0; i < len(runes); {
+		if combiningClass(runes[i]) == 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && combiningClass(runes[j]) != 0 {
+			j++
+		}
+		marks := runes[i:j]
+		sort.SliceStable(marks, func(a, b int) bool { return combiningClass(marks[a]) < combiningClass(marks[b]) })
+		i = j
+	}
+	return string(compose(runes))
+}
+
+// isNFCQuick reports strings made only of runes below U+0300, which
+// neither decompose in a way that changes them nor combine
+func isNFCQuick(s string) bool {
+	for _, r := range s {
+		if r >= 0x300 {
+			return false
+		}
+	}
+	return true
+}
+
+func combiningClass(r rune) uint8 {
+	i := sort.Search(len(combiningClasses), func(i int) bool { return combiningClasses[i].hi >= r })
+	if i < len(combiningClasses) && combiningClasses[i].lo <= r {
+		return combiningClasses[i].class
+	}
+	return 0
+}
+
+// Hangul syllables are LV or LVT sequences of jamo
+const (
+	hangulBase  = 0xAC00
+	jamoL       = 0x1100
+	jamoV       = 0x1161
+	jamoT       = 0x11A7
+	jamoLCount  = 19
+	jamoVCount  = 21
+	jamoTCount  = 28
+	hangulCount = jamoLCount * jamoVCount * jamoTCount
+)
+
+func appendDecomposed(runes []rune, r rune) []rune {
+	if s := r - hangulBase; 0 <= s && s < hangulCount {
+		runes = append(runes, jamoL+s/(jamoVCount*jamoTCount), jamoV+s%(jamoVCount*jamoTCount)/jamoTCount)
+		if t := s % jamoTCount; t != 0 {
+			runes = append(runes, jamoT+t)
+		}
+		return runes
+	}
+	if d, ok := fullDecompositions[r]; ok {
+		return append(runes, d...)
+	}
+	return append(runes, r)
+}
+
+// compose joins each mark to the last starter before it, unless a mark of
+// the same or no class comes between them; it works in place
+func compose(runes []rune) []rune {
+	out := runes[:0]
+	starter := -1
+	var lastClass uint8
+	for _, r := range runes {
+		class := combiningClass(r)
+		if starter >= 0 && (starter == len(out)-1 || lastClass < class) {
+			if c, ok := composePair(out[starter], r); ok {
+				out[starter] = c
+				continue
+			}
+		}
+		if class == 0 {
+			starter = len(out)
+		}
+		lastClass = class
+		out = append(out, r)
+	}
+	return out
+}
+
+func composePair(a, b rune) (rune, bool) {
+	if l, v := a-jamoL, b-jamoV; 0 <= l && l < jamoLCount && 0 <= v && v < jamoVCount {
+		return hangulBase + (l*jamoVCount+v)*jamoTCount, true
+	}
+	if s, t := a-hangulBase, b-jamoT; 0 <= s && s < hangulCount && s%jamoTCount == 0 && 0 < t && t < jamoTCount {
+		return a + t, true
+	}
+	c, ok := compositions[[2]rune{a, b}]
+	return c, ok
+}
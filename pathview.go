@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// ----- Path listings (-v, verify, --reverse-paths) -----
+// Paths are found and simulated from start to end. Some visualizers list
+// them from the end instead; reversePaths only turns the listings around,
+// the moves are always printed as they happen.
+
+// reversePaths is set once from the command line before anything is listed
+var reversePaths bool
+
+// displayPath returns the path in the order it is listed in
+func displayPath(p []string) []string {
+	if !reversePaths {
+		return p
+	}
+	reversed := make([]string, len(p))
+	for i, room := range p {
+		reversed[len(p)-1-i] = room
+	}
+	return reversed
+}
+
+// writePaths lists the paths one per line, numbered from 1
+func writePaths(w io.Writer, paths [][]string) {
+	for i, p := range paths {
+		fmt.Fprintf(w, "Path %d: %v (length: %d)\n", i+1, displayPath(p), len(p))
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ----- Fill, steady and drain phases of each path (--stats) -----
+// A path works like a pipeline. While it fills, ants enter it but none
+// has reached the end yet; in the steady state one ant enters and one
+// arrives on the same turn; while it drains, the last ants arrive and no
+// more enter. A path shorter than its queue of ants spends most turns
+// steady, while a long path that gets few ants is filling or draining the
+// whole time, and only pays off if the others would have needed more
+// turns for its ants.
+type pathPhase struct {
+	ants                          int
+	firstDeparture, lastDeparture int
+	firstArrival, lastArrival     int
+}
+
+// Width of the timelines, in characters
+const phaseTimelineWidth = 60
+
+// observePhases records when ants leave the start and reach the end on
+// each path
+func (c *statsCollector) observePhases(m Move, from string) {
+	if m.Path < 0 || m.Path >= len(c.phases) {
+		return
+	}
+	p := &c.phases[m.Path]
+	if from == c.farm.Start {
+		p.ants++
+		if p.firstDeparture == 0 {
+			p.firstDeparture = c.turn
+		}
+		p.lastDeparture = c.turn
+	}
+	if m.Room == c.farm.End {
+		if p.firstArrival == 0 {
+			p.firstArrival = c.turn
+		}
+		p.lastArrival = c.turn
+	}
+}
+
+// fill, steady and drain return the turns of each phase, first to last;
+// a phase that does not happen has last < first
+func (p pathPhase) fill() (int, int) {
+	last := p.lastDeparture
+	if p.firstArrival-1 < last {
+		last = p.firstArrival - 1
+	}
+	return p.firstDeparture, last
+}
+
+func (p pathPhase) steady() (int, int) { return p.firstArrival, p.lastDeparture }
+
+func (p pathPhase) drain() (int, int) {
+	first := p.lastDeparture + 1
+	if p.firstArrival > first {
+		first = p.firstArrival
+	}
+	return first, p.lastArrival
+}
+
+// at returns the timeline character of a turn: > filling, = steady,
+// < draining, - ants travelling but none entering or arriving
+func (p pathPhase) at(turn int) byte {
+	in := func(first, last int) bool { return first <= turn && turn <= last }
+	switch {
+	case p.ants == 0 || turn < p.firstDeparture || turn > p.lastArrival:
+		return ' '
+	case in(p.steady()):
+		return '='
+	case in(p.fill()):
+		return '>'
+	case in(p.drain()):
+		return '<'
+	}
+	return '-'
+}
+
+func writePhases(w io.Writer, c *statsCollector) {
+	fmt.Fprintln(w, "Path phases (> filling, = steady, < draining, - in transit):")
+	width := c.turn
+	if width > phaseTimelineWidth {
+		width = phaseTimelineWidth
+	}
+	for i, p := range c.phases {
+		tunnels := countOf(len(c.paths[i])-1, "tunnel")
+		if p.ants == 0 {
+			fmt.Fprintf(w, "  Path %d: %s, no ants\n", i+1, tunnels)
+			continue
+		}
+		var line strings.Builder
+		for col := 0; col < width; col++ {
+			// the turn in the middle of the column
+			line.WriteByte(p.at((2*col+1)*c.turn/(2*width) + 1))
+		}
+		fmt.Fprintf(w, "  Path %d: %s, %s: fill %s, steady %s, drain %s\n",
+			i+1, tunnels, countOf(p.ants, "ant"), turnRange(p.fill()), turnRange(p.steady()), turnRange(p.drain()))
+		fmt.Fprintf(w, "    |%s|\n", line.String())
+	}
+}
+
+// countOf writes n things, in the singular for one
+func countOf(n int, thing string) string {
+	if n == 1 {
+		return "1 " + thing
+	}
+	return fmt.Sprintf("%d %ss", n, thing)
+}
+
+func turnRange(first, last int) string {
+	switch {
+	case last < first:
+		return "none"
+	case first == last:
+		return fmt.Sprintf("turn %d", first)
+	}
+	return fmt.Sprintf("turns %d-%d", first, last)
+}
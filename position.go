@@ -0,0 +1,68 @@
+package main
+
+// ----- Where is an ant on a given turn -----
+type antRoute struct {
+	path      int
+	departure int // turn on which the ant leaves the start room, 0 if unknown
+}
+
+// PositionAt returns the room ant is in at the end of turn (turn 0 is
+// before the first move). Once it has left the start room an ant moves
+// on every turn, so only its path and departure turn are needed: after
+// a first call, which indexes the ants, it takes constant time. Those
+// come from Turns when they are set, otherwise ant k of a path is taken
+// to leave on turn k as in a planned distribution.
+//
+// The result is wrong for solutions where ants wait on the way (--chaos).
+// ok is false for an ant that is not in the distribution or a negative turn.
+func (s *Solution) PositionAt(ant, turn int) (room string, ok bool) {
+	if s.routes == nil {
+		s.indexRoutes()
+	}
+	if ant < 1 || ant >= len(s.routes) || turn < 0 || s.routes[ant].departure == 0 {
+		return "", false
+	}
+	r := s.routes[ant]
+	path := s.Paths[r.path]
+	step := turn - r.departure + 1
+	switch {
+	case step <= 0:
+		return path[0], true
+	case step >= len(path)-1:
+		return path[len(path)-1], true
+	}
+	return path[step], true
+}
+
+func (s *Solution) indexRoutes() {
+	ants := 0
+	for _, group := range s.Distribution {
+		for _, ant := range group {
+			if ant > ants {
+				ants = ant
+			}
+		}
+	}
+	s.routes = make([]antRoute, ants+1)
+	for path, group := range s.Distribution {
+		for k, ant := range group {
+			if ant > 0 {
+				s.routes[ant] = antRoute{path: path, departure: k + 1}
+			}
+		}
+	}
+	if len(s.Turns) == 0 {
+		return
+	}
+
+	// The first move of each ant gives its real departure turn
+	departed := make([]bool, ants+1)
+	for t, turn := range s.Turns {
+		for _, m := range turn {
+			if m.Ant > 0 && m.Ant <= ants && !departed[m.Ant] {
+				departed[m.Ant] = true
+				s.routes[m.Ant].departure = t + 1
+			}
+		}
+	}
+}
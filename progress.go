@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// Exit code used when the run is stopped with Ctrl-C (128 + SIGINT)
+const exitInterrupted = 130
+
+var errInterrupted = errors.New("interrupted")
+
+// ----- Progress structure: how far the run got -----
+type progress struct {
+	phase       atomic.Value // string
+	paths       atomic.Int64
+	turns       atomic.Int64
+	interrupted atomic.Bool
+
+	// wall-clock per phase, only touched by the main goroutine
+	timings    []phaseTiming
+	phaseStart time.Time
+
+	// memory snapshots at the end of each phase (--mem-stats)
+	memStats bool
+	lastMem  runtime.MemStats
+}
+
+type phaseTiming struct {
+	phase    string
+	duration time.Duration
+	mem      memSnapshot
+}
+
+type memSnapshot struct {
+	heapAlloc   uint64 // live heap at the end of the phase
+	allocated   uint64 // bytes allocated during the phase
+	liveObjects uint64
+	heapSys     uint64 // heap memory obtained from the OS so far
+}
+
+func newProgress() *progress {
+	p := &progress{phaseStart: time.Now()}
+	p.phase.Store("startup")
+	return p
+}
+
+// enter closes the current phase and starts timing the next one
+func (p *progress) enter(phase string) {
+	p.finish()
+	p.phase.Store(phase)
+}
+
+// finish records the time spent in the current phase
+func (p *progress) finish() {
+	now := time.Now()
+	var mem memSnapshot
+	if p.memStats {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		mem = memSnapshot{
+			heapAlloc:   m.HeapAlloc,
+			allocated:   m.TotalAlloc - p.lastMem.TotalAlloc,
+			liveObjects: m.Mallocs - m.Frees,
+			heapSys:     m.HeapSys,
+		}
+		p.lastMem = m
+	}
+	if current := p.phase.Load().(string); current != "startup" {
+		p.timings = append(p.timings, phaseTiming{current, now.Sub(p.phaseStart), mem})
+	}
+	p.phaseStart = now
+}
+
+func (p *progress) report() string {
+	return fmt.Sprintf("Interrupted during %s: %d paths found, %d turns emitted",
+		p.phase.Load(), p.paths.Load(), p.turns.Load())
+}
+
+// ----- Catch Ctrl-C and stop cleanly -----
+// During the simulation the output loop notices the flag, flushes what it
+// already wrote and stops; earlier phases have nothing to flush, so the
+// handler reports and exits right away.
+func handleInterrupt(p *progress) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		<-c
+		p.interrupted.Store(true)
+		if p.phase.Load() != "simulation" {
+			fmt.Fprintln(os.Stderr, p.report())
+			os.Exit(exitInterrupted)
+		}
+	}()
+}
@@ -0,0 +1,74 @@
+package main
+
+// ----- Drop rooms that cannot be reached from start -----
+// Returns the pruned farm and the number of rooms and tunnels removed.
+func pruneUnreachable(f *Farm) (*Farm, int, int, error) {
+	reached := map[string]bool{f.Start: true}
+	queue := []string{f.Start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range f.Rooms[current].Links {
+			if !reached[next] {
+				reached[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	if !reached[f.End] {
+		return nil, 0, 0, noPathError(codeEndUnreachable, f.End)
+	}
+	pruned := f.subFarm(func(name string) bool { return reached[name] }, nil)
+	return pruned, f.Stats().Rooms - pruned.Stats().Rooms, f.Stats().Tunnels - pruned.Stats().Tunnels, nil
+}
+
+// subFarm rebuilds the farm with only the rooms for which keep is true,
+// and the tunnels and zone memberships between them. When keepTunnel is
+// set, it also has to accept a tunnel for it to be kept.
+func (f *Farm) subFarm(keep func(name string) bool, keepTunnel func(a, b string) bool) *Farm {
+	sub := newFarm()
+	sub.Ants, sub.Start, sub.End = f.Ants, f.Start, f.End
+	sub.Spawns = append(sub.Spawns, f.Spawns...)
+	for _, name := range f.Names {
+		if keep(name) {
+			room := f.Rooms[name]
+			sub.addRoom(name, room.X, room.Y).Zone = room.Zone
+		}
+	}
+	for _, t := range f.tunnelList() {
+		if keep(t[0]) && keep(t[1]) && (keepTunnel == nil || keepTunnel(t[0], t[1])) {
+			sub.addTunnel(sub.Rooms[t[0]], sub.Rooms[t[1]])
+		}
+	}
+	for _, zone := range f.Zones {
+		kept := Zone{Name: zone.Name}
+		for _, name := range zone.Rooms {
+			if keep(name) {
+				kept.Rooms = append(kept.Rooms, sub.Rooms[name].Name)
+			}
+		}
+		if len(kept.Rooms) > 0 {
+			sub.Zones = append(sub.Zones, kept)
+		}
+	}
+	return sub
+}
+
+// ----- Reduce the farm to the chosen paths (--extract-solution-subgraph) -----
+// Keeps the rooms and tunnels the paths go through, to make a small map
+// out of a giant one when reporting a solver bug.
+func solutionSubgraph(f *Farm, paths [][]string) *Farm {
+	rooms := map[string]bool{f.Start: true, f.End: true}
+	tunnels := make(map[[2]string]bool)
+	for _, path := range paths {
+		for i, room := range path {
+			rooms[room] = true
+			if i > 0 {
+				tunnels[[2]string{path[i-1], room}] = true
+			}
+		}
+	}
+	return f.subFarm(func(name string) bool { return rooms[name] }, func(a, b string) bool {
+		return tunnels[[2]string{a, b}] || tunnels[[2]string{b, a}]
+	})
+}
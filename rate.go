@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// ----- rate: difficulty score of a map -----
+// lem-in rate map.txt
+// Four parts of up to 25 points each, for 100 in all:
+//   - size: rooms and tunnels, on a log scale (100000 of them is full)
+//   - width: the disjoint paths the minimum cut allows; every extra path
+//     is one more queue to balance
+//   - spread: how much the lengths of the flow's paths differ, as their
+//     standard deviation over their mean (0.5 is full)
+//   - trap: how many more turns the greedy non-overlapping search needs
+//     than the flow, relative to the flow (25% more is full)
+//
+// Teachers can use it to put maps in order of difficulty; the parts are
+// printed so that a score can be argued with.
+const ratePart = 25
+
+type mapRating struct {
+	rooms, tunnels  int
+	width           int
+	shortest        int // tunnels, over the flow's paths
+	longest         int
+	spread          float64
+	greedy, flow    int // turns
+	size, wide      int // points
+	uneven, trapped int
+}
+
+func (r mapRating) score() int { return r.size + r.wide + r.uneven + r.trapped }
+
+func (r mapRating) grade() string {
+	switch score := r.score(); {
+	case score < 25:
+		return "easy"
+	case score < 50:
+		return "medium"
+	case score < 75:
+		return "hard"
+	}
+	return "very hard"
+}
+
+// points scales value to ratePart points, full at value == full
+func points(value, full float64) int {
+	if value <= 0 {
+		return 0
+	}
+	if value >= full {
+		return ratePart
+	}
+	return int(math.Round(ratePart * value / full))
+}
+
+func rateFarm(f *Farm) (mapRating, error) {
+	stats := f.Stats()
+	r := mapRating{rooms: stats.Rooms, tunnels: stats.Tunnels, width: minCut(f).size()}
+	flow, _ := disjointPaths(flowPaths(f))
+	greedy, _ := disjointPaths(findNonOverlappingPaths(f))
+	if len(flow) == 0 {
+		return r, errNoPath
+	}
+	r.flow = predictTurns(flow, f.Ants, 0)
+	r.greedy = r.flow
+	if len(greedy) > 0 {
+		r.greedy = predictTurns(greedy, f.Ants, 0)
+	}
+
+	var sum, squares float64
+	for i, p := range flow {
+		length := len(p) - 1
+		if i == 0 || length < r.shortest {
+			r.shortest = length
+		}
+		if length > r.longest {
+			r.longest = length
+		}
+		sum += float64(length)
+		squares += float64(length * length)
+	}
+	mean := sum / float64(len(flow))
+	r.spread = math.Sqrt(squares/float64(len(flow))-mean*mean) / mean
+
+	r.size = points(math.Log10(float64(r.rooms+r.tunnels)), 5)
+	r.wide = points(float64(r.width-1), 4)
+	r.uneven = points(r.spread, 0.5)
+	r.trapped = points(float64(r.greedy-r.flow)/float64(r.flow), 0.25)
+	return r, nil
+}
+
+func runRate(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: go run . rate input.txt")
+		return
+	}
+	farm, err := parseInput(args[0], ParseOptions{})
+	if err != nil {
+		reportError(err)
+		return
+	}
+	r, err := rateFarm(farm)
+	if err != nil {
+		reportError(err)
+		return
+	}
+	trap := fmt.Sprintf("greedy search %d turns, flow %d", r.greedy, r.flow)
+	if r.greedy <= r.flow {
+		trap = fmt.Sprintf("none, both searches need %d turns", r.flow)
+	}
+	fmt.Printf("%-14s%-40s%2d/%d\n", "Size:", fmt.Sprintf("%d rooms, %d tunnels", r.rooms, r.tunnels), r.size, ratePart)
+	fmt.Printf("%-14s%-40s%2d/%d\n", "Width:", fmt.Sprintf("at most %s", countOf(r.width, "disjoint path")), r.wide, ratePart)
+	fmt.Printf("%-14s%-40s%2d/%d\n", "Spread:", fmt.Sprintf("paths of %d to %d tunnels (%.2f)", r.shortest, r.longest, r.spread), r.uneven, ratePart)
+	fmt.Printf("%-14s%-40s%2d/%d\n", "Greedy trap:", trap, r.trapped, ratePart)
+	fmt.Printf("%-14s%d/100, %s\n", "Difficulty:", r.score(), r.grade())
+}
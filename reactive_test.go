@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// loadTestMap parses a map of testdata/valid
+func loadTestMap(t *testing.T, name string) *Farm {
+	t.Helper()
+	farm, err := parseInput(filepath.Join("testdata", "valid", name+".txt"), ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return farm
+}
+
+// chainFarm builds a farm of separate paths of the given lengths, in
+// tunnels, from s to e; only the first path may be the direct tunnel
+func chainFarm(lengths []int, ants int) (*Farm, [][]string) {
+	f := newFarm()
+	f.Ants = ants
+	start := f.addRoom("s", 0, 0)
+	end := f.addRoom("e", 0, 0)
+	f.Start, f.End = "s", "e"
+	var paths [][]string
+	for i, length := range lengths {
+		if length == 1 && i > 0 {
+			continue
+		}
+		prev := start
+		path := []string{"s"}
+		for k := 1; k < length; k++ {
+			room := f.addRoom(fmt.Sprintf("r%d_%d", i, k), 0, 0)
+			f.addTunnel(prev, room)
+			prev = room
+			path = append(path, room.Name)
+		}
+		f.addTunnel(prev, end)
+		paths = append(paths, append(path, "e"))
+	}
+	return f, paths
+}
+
+func TestReactiveMatchesStatic(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 400; i++ {
+		lengths := make([]int, 1+rng.Intn(5))
+		for j := range lengths {
+			lengths[j] = 1 + rng.Intn(8)
+		}
+		ants := 1 + rng.Intn(60)
+		f, paths := chainFarm(lengths, ants)
+		maxPerPath := 0
+		if i%3 == 0 {
+			maxPerPath = (ants+len(paths)-1)/len(paths) + rng.Intn(3)
+		}
+		static := len(simulateAnts(paths, distributeAnts(ants, paths, maxPerPath)))
+		sim := newReactiveSimulator(paths, ants, maxPerPath)
+		turns := collectTurns(sim.Step)
+		sol := Solution{Farm: f, Paths: paths, Distribution: sim.Distribution(), Turns: turns}
+		if err := checkSolution(sol); err != nil {
+			t.Fatalf("lengths %v, %d ants: %v", lengths, ants, err)
+		}
+		if len(turns) != static {
+			t.Errorf("lengths %v, %d ants, at most %d per path: %d turns static, %d reactive",
+				lengths, ants, maxPerPath, static, len(turns))
+		}
+	}
+}
+
+func TestReactiveMaps(t *testing.T) {
+	for _, name := range []string{"ex1", "ex2", "ex3", "zones", "share"} {
+		f := loadTestMap(t, name)
+		paths := autoPaths(f)
+		static := len(simulateAnts(paths, distributeAnts(f.Ants, paths, 0)))
+		reactive := len(collectTurns(newReactiveSimulator(paths, f.Ants, 0).Step))
+		if reactive > static {
+			t.Errorf("%s: %d turns static, %d reactive", name, static, reactive)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ----- Compressed replays (--replay file) -----
+// Once it has left the start room an ant moves on every turn, so a replay
+// only needs the paths and, for each path, which ant leaves on which turn.
+// Departures are stored as runs: ants A, A+D, A+2D, ... leaving on N
+// consecutive turns from turn T. A replay file reads:
+//
+//	#lem-in replay 1
+//	path start a b end
+//	run 0 1 500000 1 2      (path, T, N, A, D)
+//
+// Runs of a path are listed in turn order.
+const replayHeader = "#lem-in replay 1"
+
+type departureRun struct {
+	path, turn, count, ant, delta int
+}
+
+type replayAnt struct {
+	path, step int
+}
+
+// replayEncoder rebuilds the departures from the emitted turns. It keeps
+// only the ants on their way, not the whole schedule.
+type replayEncoder struct {
+	paths   [][]string
+	byFirst map[string]int // path index by the first room after start
+	active  map[int]*replayAnt
+	runs    [][]departureRun
+	turn    int
+	err     error
+}
+
+func newReplayEncoder(paths [][]string) *replayEncoder {
+	e := &replayEncoder{
+		paths:   paths,
+		byFirst: make(map[string]int),
+		active:  make(map[int]*replayAnt),
+		runs:    make([][]departureRun, len(paths)),
+	}
+	for i, path := range paths {
+		e.byFirst[path[1]] = i
+	}
+	return e
+}
+
+func (e *replayEncoder) observe(turn Turn) {
+	e.turn++
+	if e.err != nil {
+		return
+	}
+	waiting, moved := len(e.active), 0
+	for _, m := range turn {
+		if a := e.active[m.Ant]; a != nil {
+			a.step++
+			if m.Room != e.paths[a.path][a.step] {
+				e.err = fmt.Errorf("ant %d left its path on turn %d", m.Ant, e.turn)
+				return
+			}
+			moved++
+			if a.step == len(e.paths[a.path])-1 {
+				delete(e.active, m.Ant)
+			}
+			continue
+		}
+		path, ok := e.byFirst[m.Room]
+		if !ok {
+			e.err = fmt.Errorf("ant %d entered %s from the start room on turn %d, which starts no path", m.Ant, m.Room, e.turn)
+			return
+		}
+		e.depart(path, m.Ant)
+		if len(e.paths[path]) > 2 {
+			e.active[m.Ant] = &replayAnt{path: path, step: 1}
+		}
+	}
+	if moved != waiting {
+		e.err = fmt.Errorf("an ant waited on turn %d: replays need ants that move every turn", e.turn)
+	}
+}
+
+// depart extends the last run of the path when it can
+func (e *replayEncoder) depart(path, ant int) {
+	runs := e.runs[path]
+	if n := len(runs); n > 0 {
+		last := &runs[n-1]
+		if e.turn == last.turn+last.count && (last.count == 1 || ant == last.ant+last.count*last.delta) {
+			if last.count == 1 {
+				last.delta = ant - last.ant
+			}
+			last.count++
+			return
+		}
+	}
+	e.runs[path] = append(runs, departureRun{path: path, turn: e.turn, count: 1, ant: ant})
+}
+
+func (e *replayEncoder) writeTo(w io.Writer) error {
+	if e.err != nil {
+		return e.err
+	}
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, replayHeader)
+	for _, path := range e.paths {
+		quoted := make([]string, len(path))
+		for i, name := range path {
+			quoted[i] = quoteName(name)
+		}
+		fmt.Fprintln(out, "path", strings.Join(quoted, " "))
+	}
+	for _, runs := range e.runs {
+		for _, r := range runs {
+			fmt.Fprintln(out, "run", r.path, r.turn, r.count, r.ant, r.delta)
+		}
+	}
+	return out.Flush()
+}
+
+func writeReplayFile(filename string, e *replayEncoder) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = e.writeTo(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ----- Read a replay back -----
+func loadReplay(filename string) ([][]string, []departureRun, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var paths [][]string
+	var runs []departureRun
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<24)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != replayHeader {
+		return nil, nil, fmt.Errorf("%s is not a replay file", filename)
+	}
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 0:
+			continue
+		case fields[0] == "path" && len(fields) >= 3:
+			path := make([]string, len(fields)-1)
+			for i, field := range fields[1:] {
+				name, err := unquoteName(field)
+				if err != nil {
+					return nil, nil, err
+				}
+				path[i] = name
+			}
+			paths = append(paths, path)
+		case fields[0] == "run" && len(fields) == 6:
+			var n [5]int
+			for i, field := range fields[1:] {
+				if n[i], err = strconv.Atoi(field); err != nil {
+					return nil, nil, fmt.Errorf("invalid replay line: %q", scanner.Text())
+				}
+			}
+			r := departureRun{path: n[0], turn: n[1], count: n[2], ant: n[3], delta: n[4]}
+			if r.path < 0 || r.path >= len(paths) || r.turn < 1 || r.count < 1 {
+				return nil, nil, fmt.Errorf("invalid replay line: %q", scanner.Text())
+			}
+			runs = append(runs, r)
+		default:
+			return nil, nil, fmt.Errorf("invalid replay line: %q", scanner.Text())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return paths, runs, nil
+}
+
+// replayTurnsFrom expands the runs turn by turn. Within a turn the moves
+// are listed path by path, the ant furthest along first, as the
+// simulator does for a planned distribution.
+func replayTurnsFrom(paths [][]string, runs []departureRun) func() (Turn, bool) {
+	pending := make([][]departureRun, len(paths)) // runs of each path not fully started
+	for _, r := range runs {
+		pending[r.path] = append(pending[r.path], r)
+	}
+	onPath := make([][]antPosition, len(paths))
+	turn := 0
+	return func() (Turn, bool) {
+		turn++
+		var moves Turn
+		for i, path := range paths {
+			var still []antPosition
+			for _, pos := range onPath[i] {
+				pos.step++
+				moves = append(moves, Move{Ant: pos.ant, Room: path[pos.step], Path: i})
+				if pos.step < len(path)-1 {
+					still = append(still, pos)
+				}
+			}
+			for len(pending[i]) > 0 && pending[i][0].turn+pending[i][0].count <= turn {
+				pending[i] = pending[i][1:]
+			}
+			if len(pending[i]) > 0 && pending[i][0].turn <= turn {
+				r := pending[i][0]
+				ant := r.ant + (turn-r.turn)*r.delta
+				moves = append(moves, Move{Ant: ant, Room: path[1], Path: i})
+				if len(path) > 2 {
+					still = append(still, antPosition{ant: ant, path: i, step: 1})
+				}
+			}
+			onPath[i] = still
+		}
+		return moves, len(moves) > 0
+	}
+}
+
+// ----- replay: print the moves stored in a replay file -----
+// lem-in replay file
+func runReplay(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: go run . replay file")
+		return
+	}
+	paths, runs, err := loadReplay(args[0])
+	if err != nil {
+		reportError(err)
+		return
+	}
+	prog := newProgress()
+	handleInterrupt(prog)
+	prog.enter("simulation")
+	err = streamAnts(os.Stdout, writeTurn, replayTurnsFrom(paths, runs), prog, nil)
+	if errors.Is(err, errInterrupted) {
+		fmt.Fprintln(os.Stderr, prog.report())
+		os.Exit(exitInterrupted)
+	}
+	if err != nil && !errors.Is(err, syscall.EPIPE) {
+		writeError(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+)
+
+// ----- Repeated runs with shuffled tunnels (--runs N) -----
+// The searches break ties by the order of the tunnels in the map. Each
+// run solves a copy of the farm with every room's tunnels shuffled by its
+// own seed (--seed, --seed+1, ...), which shows how much the turn count
+// depends on those ties. Runs are compared by the turns the balanced
+// distribution needs, and the paths of the best one are kept.
+type runsReport struct {
+	turns    []int // of every run that found a path, sorted
+	bestSeed int64
+}
+
+func bestOfRuns(f *Farm, strategy Strategy, runs int, seed int64) ([][]string, runsReport) {
+	var best [][]string
+	var report runsReport
+	bestTurns := -1
+	for i := 0; i < runs; i++ {
+		runSeed := seed + int64(i)
+		paths, _ := disjointPaths(strategy(shuffledTunnels(f, rand.New(rand.NewSource(runSeed)))))
+		if len(paths) == 0 {
+			continue
+		}
+		turns := predictTurns(paths, f.Ants, maxAntsPerPath)
+		report.turns = append(report.turns, turns)
+		if bestTurns < 0 || turns < bestTurns {
+			best, bestTurns, report.bestSeed = paths, turns, runSeed
+		}
+	}
+	sort.Ints(report.turns)
+	return best, report
+}
+
+func (r runsReport) median() float64 {
+	n := len(r.turns)
+	if n%2 == 1 {
+		return float64(r.turns[n/2])
+	}
+	return float64(r.turns[n/2-1]+r.turns[n/2]) / 2
+}
+
+func (r runsReport) write(w io.Writer, runs int) {
+	if len(r.turns) == 0 {
+		fmt.Fprintf(w, "Runs: %d, none found a path\n", runs)
+		return
+	}
+	fmt.Fprintf(w, "Runs: %d, turns min %d, median %g, max %d (best with seed %d)\n",
+		runs, r.turns[0], r.median(), r.turns[len(r.turns)-1], r.bestSeed)
+}
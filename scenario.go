@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ----- Scenario files (lem-in scenario file) -----
+// A scenario replays a dynamic demo from one file: the map to load and
+// the ants that join the run later, one operation a line:
+//
+//	# comments and blank lines are skipped
+//	load maps/ex1.txt
+//	add 50 at turn 20
+//
+// The map path is relative to the scenario file. Each "add" works like a
+// ##spawn line of the map, and the run uses the reactive scheduler, which
+// sends the new ants as they come. Closing a tunnel during the run is not
+// supported yet: ants follow paths chosen before the first turn.
+type scenario struct {
+	mapFile string
+	spawns  []Spawn
+}
+
+func parseScenario(filename string) (scenario, error) {
+	var sc scenario
+	file, err := os.Open(filename)
+	if err != nil {
+		return sc, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "load":
+			if len(fields) != 2 || sc.mapFile != "" {
+				return sc, fmt.Errorf("%s:%d: expected one \"load map.txt\" line", filename, lineNo)
+			}
+			sc.mapFile = fields[1]
+			if !filepath.IsAbs(sc.mapFile) {
+				sc.mapFile = filepath.Join(filepath.Dir(filename), sc.mapFile)
+			}
+		case "add":
+			spawn, ok := parseAdd(fields)
+			if !ok {
+				return sc, fmt.Errorf("%s:%d: invalid line %q, expected \"add N at turn T\"", filename, lineNo, line)
+			}
+			sc.spawns = append(sc.spawns, spawn)
+		case "close":
+			return sc, fmt.Errorf("%s:%d: closing tunnels during a run is not supported yet", filename, lineNo)
+		default:
+			return sc, fmt.Errorf("%s:%d: unknown operation %q", filename, lineNo, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return sc, err
+	}
+	if sc.mapFile == "" {
+		return sc, fmt.Errorf("%s: no \"load\" line", filename)
+	}
+	return sc, nil
+}
+
+// parseAdd reads the fields of an "add N at turn T" line
+func parseAdd(fields []string) (Spawn, bool) {
+	if len(fields) != 5 || fields[2] != "at" || fields[3] != "turn" {
+		return Spawn{}, false
+	}
+	ants, err1 := strconv.Atoi(fields[1])
+	turn, err2 := strconv.Atoi(fields[4])
+	if err1 != nil || err2 != nil || ants < 1 || turn < 1 {
+		return Spawn{}, false
+	}
+	return Spawn{Ants: ants, Turn: turn}, true
+}
+
+func runScenario(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: go run . scenario scenario.txt")
+		return
+	}
+	sc, err := parseScenario(args[0])
+	if err != nil {
+		reportError(err)
+		return
+	}
+	farm, err := parseInput(sc.mapFile, ParseOptions{AllowZeroAnts: len(sc.spawns) > 0})
+	if err != nil {
+		reportError(err)
+		return
+	}
+	farm.Spawns = append(farm.Spawns, sc.spawns...)
+	paths, _ := disjointPaths(autoPaths(farm))
+	if len(paths) == 0 {
+		reportError(errNoPath)
+		return
+	}
+
+	prog := newProgress()
+	handleInterrupt(prog)
+	prog.paths.Store(int64(len(paths)))
+	fmt.Println(farm.String())
+	prog.enter("simulation")
+	next := spawnSteps(newReactiveSimulator(paths, farm.Ants, 0), farm.Spawns)
+	err = streamAnts(os.Stdout, writeTurn, next, prog, nil)
+	if errors.Is(err, errInterrupted) {
+		fmt.Fprintln(os.Stderr, prog.report())
+		os.Exit(exitInterrupted)
+	}
+	if err != nil && !errors.Is(err, syscall.EPIPE) {
+		writeError(os.Stderr, err)
+		os.Exit(1)
+	}
+}
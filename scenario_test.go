@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseScenario(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		content string
+		spawns  int
+		ok      bool
+	}{
+		{"# demo\nload ex3.txt\n\nadd 50 at turn 20\nadd 1 at turn 2\n", 2, true},
+		{"load ex3.txt\n", 0, true},
+		{"add 5 at turn 2\n", 0, false},
+		{"load a.txt\nload b.txt\n", 0, false},
+		{"load ex3.txt\nadd 0 at turn 2\n", 0, false},
+		{"load ex3.txt\nadd 5 ants at turn 2\n", 0, false},
+		{"load ex3.txt\nclose a-b at turn 10\n", 0, false},
+	}
+	for _, tt := range tests {
+		file := filepath.Join(dir, "scenario.txt")
+		if err := os.WriteFile(file, []byte(tt.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		sc, err := parseScenario(file)
+		switch {
+		case (err == nil) != tt.ok:
+			t.Errorf("%q: got error %v", tt.content, err)
+		case err == nil && (len(sc.spawns) != tt.spawns || sc.mapFile != filepath.Join(dir, "ex3.txt")):
+			t.Errorf("%q: map %s, %d spawns, want %d", tt.content, sc.mapFile, len(sc.spawns), tt.spawns)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build selfcheck
+
+package main
+
+// CI and test builds check every schedule before printing it
+func init() {
+	defaultSelfCheck = true
+}
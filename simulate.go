@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// ----- simulate: run the distribution and simulation over given paths -----
+// lem-in simulate map.txt --paths paths.json [--ants N]
+// paths.json holds a list of paths, each a list of room names from start
+// to end, e.g. [["start","a","end"],["start","b","c","end"]].
+func runSimulate(args []string) {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	pathsFile := fs.String("paths", "", "JSON file with the paths to use")
+	ants := fs.Int("ants", -1, "number of ants, replacing the count from the map (which may then be 0)")
+	args, _ = parseFlags(fs, args)
+	if len(args) < 1 || *pathsFile == "" {
+		fmt.Println("Usage: go run . simulate input.txt --paths paths.json [--ants N]")
+		return
+	}
+	antsSet := false
+	fs.Visit(func(f *flag.Flag) { antsSet = antsSet || f.Name == "ants" })
+	if antsSet && *ants < 0 {
+		reportErrorf("--ants must not be negative")
+		return
+	}
+
+	farm, err := parseInput(args[0], ParseOptions{AllowZeroAnts: antsSet})
+	if err != nil {
+		reportError(err)
+		return
+	}
+	if antsSet {
+		farm.Ants = *ants
+	}
+	paths, err := loadPaths(*pathsFile)
+	if err != nil {
+		reportError(err)
+		return
+	}
+	if err := validatePaths(farm, paths); err != nil {
+		reportError(err)
+		return
+	}
+
+	prog := newProgress()
+	handleInterrupt(prog)
+	prog.paths.Store(int64(len(paths)))
+	prog.enter("simulation")
+	sim := newSimulator(paths, distributeAnts(farm.Ants, paths, 0))
+	err = streamAnts(os.Stdout, writeTurn, sim.Step, prog, nil)
+	if errors.Is(err, errInterrupted) {
+		fmt.Fprintln(os.Stderr, prog.report())
+		os.Exit(exitInterrupted)
+	}
+	if err != nil && !errors.Is(err, syscall.EPIPE) {
+		writeError(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func loadPaths(filename string) ([][]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var paths [][]string
+	if err := json.Unmarshal(data, &paths); err != nil {
+		return nil, fmt.Errorf("invalid paths file %s: %v", filename, err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("paths file %s has no paths", filename)
+	}
+	for _, path := range paths {
+		for i, name := range path {
+			path[i] = nfc(name)
+		}
+	}
+	return paths, nil
+}
+
+// ----- Check that paths go from start to end through existing tunnels -----
+// and that no two paths share an intermediate room.
+func validatePaths(f *Farm, paths [][]string) error {
+	usedBy := make(map[string]int)
+	for i, path := range paths {
+		if len(path) < 2 || path[0] != f.Start || path[len(path)-1] != f.End {
+			return fmt.Errorf("path %d must go from %s to %s", i+1, f.Start, f.End)
+		}
+		for j := 1; j < len(path); j++ {
+			room := f.Rooms[path[j]]
+			if room == nil {
+				return fmt.Errorf("path %d: unknown room %q", i+1, path[j])
+			}
+			if !hasLink(f.Rooms[path[j-1]], path[j]) {
+				return fmt.Errorf("path %d: no tunnel %s-%s", i+1, path[j-1], path[j])
+			}
+			if j == len(path)-1 {
+				break
+			}
+			if path[j] == f.Start || path[j] == f.End {
+				return fmt.Errorf("path %d passes through %q on the way", i+1, path[j])
+			}
+			if other, used := usedBy[path[j]]; used {
+				if other == i {
+					return fmt.Errorf("path %d visits room %q twice", i+1, path[j])
+				}
+				return fmt.Errorf("paths %d and %d share room %q", other+1, i+1, path[j])
+			}
+			usedBy[path[j]] = i
+		}
+	}
+	return nil
+}
+
+func hasLink(room *Room, name string) bool {
+	for _, link := range room.Links {
+		if link == name {
+			return true
+		}
+	}
+	return false
+}
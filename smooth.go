@@ -0,0 +1,106 @@
+package main
+
+import "math"
+
+// ----- Geometrically smooth paths (--smooth) -----
+// smoothPaths replaces each path by the one of the same length, through
+// rooms no other path uses, with the shortest drawn length between the
+// room coordinates. Path lengths do not change, so neither do the turns.
+// Only paths that are shortest once the other paths are blocked have
+// alternatives considered.
+func smoothPaths(f *Farm, paths [][]string) [][]string {
+	smoothed := make([][]string, len(paths))
+	copy(smoothed, paths)
+	for i, path := range smoothed {
+		if len(path) <= 2 {
+			continue
+		}
+		blocked := make(map[string]bool)
+		for j, other := range smoothed {
+			if j != i {
+				for _, name := range other[1 : len(other)-1] {
+					blocked[name] = true
+				}
+			}
+		}
+		if better := smoothestPath(f, len(path)-1, blocked); better != nil && drawnLength(f, better) < drawnLength(f, path) {
+			smoothed[i] = better
+		}
+	}
+	return smoothed
+}
+
+// smoothestPath returns the path of the given number of tunnels with the
+// shortest drawn length, or nil if the shortest path avoiding blocked is
+// not that long. Every step of a shortest path moves one BFS layer closer
+// to the end, so the best one is found layer by layer.
+func smoothestPath(f *Farm, tunnels int, blocked map[string]bool) []string {
+	fromStart := layers(f, f.Start, blocked)
+	toEnd := layers(f, f.End, blocked)
+	if d, ok := fromStart[f.End]; !ok || d != tunnels {
+		return nil
+	}
+
+	// best[room]: shortest drawn length from start, previous room on it
+	best := map[string]float64{f.Start: 0}
+	previous := make(map[string]string)
+	layer := []string{f.Start}
+	for step := 1; step <= tunnels; step++ {
+		var next []string
+		for _, name := range layer {
+			for _, link := range f.Rooms[name].Links {
+				if blocked[link] || fromStart[link] != step || toEnd[link] != tunnels-step {
+					continue
+				}
+				length := best[name] + distance(f.Rooms[name], f.Rooms[link])
+				if old, seen := best[link]; !seen {
+					next = append(next, link)
+				} else if length >= old {
+					continue
+				}
+				best[link] = length
+				previous[link] = name
+			}
+		}
+		layer = next
+	}
+
+	path := make([]string, tunnels+1)
+	for i, name := tunnels, f.End; i >= 0; i, name = i-1, previous[name] {
+		path[i] = name
+	}
+	return path
+}
+
+// layers returns the BFS distance of every room reachable from root
+// without going through blocked rooms
+func layers(f *Farm, root string, blocked map[string]bool) map[string]int {
+	dist := map[string]int{root: 0}
+	queue := []string{root}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current != root && (current == f.Start || current == f.End) {
+			continue
+		}
+		for _, link := range f.Rooms[current].Links {
+			if _, seen := dist[link]; !seen && !blocked[link] {
+				dist[link] = dist[current] + 1
+				queue = append(queue, link)
+			}
+		}
+	}
+	return dist
+}
+
+func distance(a, b *Room) float64 {
+	return math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y))
+}
+
+func drawnLength(f *Farm, path []string) float64 {
+	total := 0.0
+	for i := 1; i < len(path); i++ {
+		total += distance(f.Rooms[path[i-1]], f.Rooms[path[i]])
+	}
+	return total
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ----- Ants arriving over time (##spawn extension) -----
+// A "##spawn N ants at turn T" line, refused by --strict, says that N more
+// ants become available in the start room on turn T: they can move that
+// turn at the earliest. The ants of the first line are there on turn 1,
+// so maps without ##spawn are solved as before. While no ant is on the
+// way and the next ones have not spawned yet, turns are empty lines.
+type Spawn struct {
+	Ants int
+	Turn int
+}
+
+// parseSpawn reads the fields of a ##spawn line
+func parseSpawn(line string) (Spawn, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 6 || fields[0] != "##spawn" || (fields[2] != "ants" && fields[2] != "ant") ||
+		fields[3] != "at" || fields[4] != "turn" {
+		return Spawn{}, codedErrorf(codeSpawnLine, line)
+	}
+	ants, err1 := strconv.Atoi(fields[1])
+	turn, err2 := strconv.Atoi(fields[5])
+	if err1 != nil || err2 != nil || ants < 1 || turn < 1 {
+		return Spawn{}, codedErrorf(codeSpawnLine, line)
+	}
+	return Spawn{Ants: ants, Turn: turn}, nil
+}
+
+// spawnedAnts is the number of ants of all the ##spawn lines
+func (f *Farm) spawnedAnts() int {
+	n := 0
+	for _, s := range f.Spawns {
+		n += s.Ants
+	}
+	return n
+}
+
+// spawnSteps wraps sim.Step to add the spawned ants on their turn.
+// Spawned ants go through AddAnts, so they are dispatched reactively.
+func spawnSteps(sim *Simulator, spawns []Spawn) func() (Turn, bool) {
+	pending := append([]Spawn(nil), spawns...)
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].Turn < pending[j].Turn })
+	turn := 0
+	return func() (Turn, bool) {
+		turn++
+		for len(pending) > 0 && pending[0].Turn <= turn {
+			sim.AddAnts(pending[0].Ants)
+			pending = pending[1:]
+		}
+		moves, ok := sim.Step()
+		if !ok && len(pending) > 0 {
+			return Turn{}, true // waiting for the next spawn
+		}
+		return moves, ok
+	}
+}
+
+// withoutSpawns turns a solution of a map with ##spawn into one that
+// checkSolution can read, which expects every ant from the start and a
+// move on every turn: the spawned ants are added to the count and the
+// turns spent waiting for them are dropped.
+func withoutSpawns(sol Solution) Solution {
+	if len(sol.Farm.Spawns) == 0 {
+		return sol
+	}
+	farm := sol.Farm.Clone()
+	farm.Ants += farm.spawnedAnts()
+	farm.Spawns = nil
+	var turns []Turn
+	for _, turn := range sol.Turns {
+		if len(turn) > 0 {
+			turns = append(turns, turn)
+		}
+	}
+	sol.Farm, sol.Turns = farm, turns
+	return sol
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// ----- Collect statistics from the emitted turns -----
+type statsCollector struct {
+	farm      *Farm
+	positions map[int]string // current room of every ant that has moved
+	moves     int            // total distance travelled by all ants
+
+	// zones
+	occupancy  map[string]int // ants currently inside each zone
+	antTurns   map[string]int // sum over turns of ants inside each zone
+	crossings  map[string]int // moves between two different zones ("a -> b")
+	zonesInUse bool
+
+	// busiest and quietest turns, the first one when several tie
+	turn              int
+	maxMoves, maxTurn int
+	minMoves, minTurn int
+	tunnels           map[[2]string]*tunnelUse
+
+	paths  [][]string
+	phases []pathPhase // by path, see phases.go
+}
+
+// A tunnel carries one ant per turn: it is saturated on every turn it is
+// used, and run counts the turns in a row it was saturated.
+type tunnelUse struct {
+	last, run   int // last turn used and the run ending there
+	longest     int
+	longestFrom int // first turn of the longest run
+}
+
+func newStatsCollector(f *Farm, paths [][]string) *statsCollector {
+	return &statsCollector{
+		farm:       f,
+		paths:      paths,
+		phases:     make([]pathPhase, len(paths)),
+		positions:  make(map[int]string),
+		occupancy:  make(map[string]int),
+		antTurns:   make(map[string]int),
+		crossings:  make(map[string]int),
+		zonesInUse: len(f.Zones) > 0,
+		tunnels:    make(map[[2]string]*tunnelUse),
+	}
+}
+
+// zoneOf returns the zone of a room; ants waiting in the start room or
+// arrived in the end room are not counted as being inside any zone.
+func (c *statsCollector) zoneOf(room string) string {
+	if room == c.farm.Start || room == c.farm.End {
+		return ""
+	}
+	return c.farm.Rooms[room].Zone
+}
+
+func (c *statsCollector) observe(turn Turn) {
+	c.moves += len(turn)
+	c.turn++
+	if c.turn == 1 || len(turn) > c.maxMoves {
+		c.maxMoves, c.maxTurn = len(turn), c.turn
+	}
+	if c.turn == 1 || len(turn) < c.minMoves {
+		c.minMoves, c.minTurn = len(turn), c.turn
+	}
+	for _, m := range turn {
+		from, ok := c.positions[m.Ant]
+		if !ok {
+			from = c.farm.Start
+		}
+		c.positions[m.Ant] = m.Room
+		c.useTunnel(from, m.Room)
+		c.observePhases(m, from)
+		if !c.zonesInUse {
+			continue
+		}
+		fromZone, toZone := c.farm.Rooms[from].Zone, c.farm.Rooms[m.Room].Zone
+		if fromZone != toZone {
+			c.crossings[zoneLabel(fromZone)+" -> "+zoneLabel(toZone)]++
+		}
+		if z := c.zoneOf(from); z != "" {
+			c.occupancy[z]--
+		}
+		if z := c.zoneOf(m.Room); z != "" {
+			c.occupancy[z]++
+		}
+	}
+	for zone, ants := range c.occupancy {
+		c.antTurns[zone] += ants
+	}
+}
+
+func (c *statsCollector) useTunnel(a, b string) {
+	key := [2]string{a, b}
+	if a > b {
+		key = [2]string{b, a}
+	}
+	use := c.tunnels[key]
+	if use == nil {
+		use = &tunnelUse{}
+		c.tunnels[key] = use
+	}
+	if use.last == c.turn-1 && use.run > 0 {
+		use.run++
+	} else {
+		use.run = 1
+	}
+	use.last = c.turn
+	if use.run > use.longest {
+		use.longest, use.longestFrom = use.run, c.turn-use.run+1
+	}
+}
+
+// Tunnels listed under "Longest saturated tunnels"
+const saturatedTunnels = 5
+
+// longestSaturated returns the tunnels saturated for the most turns in a
+// row, the earliest run first among equals.
+func (c *statsCollector) longestSaturated(n int) [][2]string {
+	keys := make([][2]string, 0, len(c.tunnels))
+	for k := range c.tunnels {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := c.tunnels[keys[i]], c.tunnels[keys[j]]
+		if a.longest != b.longest {
+			return a.longest > b.longest
+		}
+		if a.longestFrom != b.longestFrom {
+			return a.longestFrom < b.longestFrom
+		}
+		return keys[i][0]+"-"+keys[i][1] < keys[j][0]+"-"+keys[j][1]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+func zoneLabel(zone string) string {
+	if zone == "" {
+		return "(none)"
+	}
+	return zone
+}
+
+// ----- Print run statistics (--stats) -----
+func printStats(w io.Writer, prog *progress, c *statsCollector) {
+	fmt.Fprintln(w, "\n=== Stats ===")
+	fmt.Fprintf(w, "%-16s%d\n", "Paths used:", prog.paths.Load())
+	fmt.Fprintf(w, "%-16s%d\n", "Turns:", prog.turns.Load())
+	if c != nil {
+		fmt.Fprintf(w, "%-16s%d\n", "Total distance:", c.moves)
+		if c.turn > 0 {
+			fmt.Fprintf(w, "%-16s%d moves (turn %d)\n", "Busiest turn:", c.maxMoves, c.maxTurn)
+			fmt.Fprintf(w, "%-16s%d moves (turn %d)\n", "Quietest turn:", c.minMoves, c.minTurn)
+			fmt.Fprintln(w, "Longest saturated tunnels:")
+			for _, k := range c.longestSaturated(saturatedTunnels) {
+				use := c.tunnels[k]
+				fmt.Fprintf(w, "  %s-%s: %d turns (turns %d-%d)\n", k[0], k[1], use.longest, use.longestFrom, use.longestFrom+use.longest-1)
+			}
+			writePhases(w, c)
+		}
+		if sets := countOptimalSets(c.farm); sets.exact {
+			fmt.Fprintf(w, "%-16s%d with %d turns (exact)\n", "Optimal sets:", sets.count, sets.turns)
+		} else if sets.count > 0 {
+			fmt.Fprintf(w, "%-16sat least %d with %d turns (%d shuffled searches)\n", "Optimal sets:", sets.count, sets.turns, alternativeSamples)
+		}
+	}
+
+	var total time.Duration
+	fmt.Fprintln(w, "Phase timings:")
+	for _, t := range prog.timings {
+		fmt.Fprintf(w, "  %-14s %v\n", t.phase, t.duration)
+		total += t.duration
+	}
+	fmt.Fprintf(w, "  %-14s %v\n", "total", total)
+
+	if c != nil && c.zonesInUse {
+		fmt.Fprintln(w, "Ant-turns per zone:")
+		for _, zone := range c.farm.Zones {
+			fmt.Fprintf(w, "  %-14s %d\n", zone.Name, c.antTurns[zone.Name])
+		}
+		fmt.Fprintln(w, "Inter-zone tunnel usage:")
+		keys := make([]string, 0, len(c.crossings))
+		for k := range c.crossings {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "  %s: %d\n", k, c.crossings[k])
+		}
+	}
+}
+
+// ----- Print memory usage per phase (--mem-stats) -----
+func printMemStats(w io.Writer, prog *progress) {
+	fmt.Fprintln(w, "\n=== Memory ===")
+	fmt.Fprintf(w, "  %-14s %12s %12s %12s\n", "phase", "heap", "allocated", "live objects")
+	var peak uint64
+	for _, t := range prog.timings {
+		fmt.Fprintf(w, "  %-14s %12s %12s %12d\n", t.phase,
+			formatBytes(t.mem.heapAlloc), formatBytes(t.mem.allocated), t.mem.liveObjects)
+		if t.mem.heapSys > peak {
+			peak = t.mem.heapSys
+		}
+	}
+	fmt.Fprintf(w, "Peak heap obtained from the OS: %s\n", formatBytes(peak))
+}
+
+func formatBytes(n uint64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
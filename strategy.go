@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"plugin"
+	"sort"
+)
+
+// ----- Strategy registry -----
+// A Strategy finds the set of paths the ants will travel on.
+type Strategy func(f *Farm) [][]string
+
+var strategies = make(map[string]func() Strategy)
+
+// RegisterStrategy makes a strategy selectable with --algo. Forks can call
+// it from an init function; plugins are loaded with --plugin.
+func RegisterStrategy(name string, factory func() Strategy) {
+	if _, exists := strategies[name]; exists {
+		panic(fmt.Sprintf("strategy %q registered twice", name))
+	}
+	strategies[name] = factory
+}
+
+func init() {
+	RegisterStrategy("auto", func() Strategy { return autoPaths })
+	RegisterStrategy("shortest", func() Strategy {
+		return func(f *Farm) [][]string { return selectBestPaths(f, findAllShortestPaths(f)) }
+	})
+	RegisterStrategy("nonoverlap", func() Strategy { return findNonOverlappingPaths })
+	RegisterStrategy("flow", func() Strategy { return flowPaths })
+	RegisterStrategy("backward", func() Strategy { return backwardPaths })
+}
+
+func lookupStrategy(name string) (Strategy, error) {
+	factory, ok := strategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %q", name)
+	}
+	return factory(), nil
+}
+
+func strategyNames() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ----- Default strategy: run both methods and keep the better set -----
+func autoPaths(f *Farm) [][]string {
+	bestPaths := selectBestPaths(f, findAllShortestPaths(f))
+	nonOverlapPaths, _ := disjointPaths(findNonOverlappingPaths(f))
+	nonOverlapPaths, _, _ = preferBackward(f, nonOverlapPaths)
+	paths, _ := pickPaths(f.Ants, maxAntsPerPath, nonOverlapPaths, bestPaths)
+	return paths
+}
+
+// pickPaths keeps the set needing fewer turns for this many ants, with at
+// most maxPerPath on a path, the shortest-path selection on a tie, and
+// says why. More paths are not always better: with few ants, longer extra
+// paths only add turns.
+func pickPaths(ants, maxPerPath int, nonOverlapPaths, bestPaths [][]string) ([][]string, string) {
+	switch {
+	case len(nonOverlapPaths) == 0:
+		return bestPaths, "the non-conflicting paths (the non-overlapping search found none)"
+	case len(bestPaths) == 0:
+		return nonOverlapPaths, "the non-overlapping paths (the shortest-path selection found none)"
+	}
+	nonOverlapTurns, bestTurns := predictTurns(nonOverlapPaths, ants, maxPerPath), predictTurns(bestPaths, ants, maxPerPath)
+	if nonOverlapTurns < bestTurns {
+		return nonOverlapPaths, fmt.Sprintf("the non-overlapping paths (%s instead of %s for %d ants)", turnsText(nonOverlapTurns), turnsText(bestTurns), ants)
+	}
+	if nonOverlapTurns == bestTurns {
+		return bestPaths, fmt.Sprintf("the non-conflicting paths (both sets need %s for %d ants)", turnsText(bestTurns), ants)
+	}
+	return bestPaths, fmt.Sprintf("the non-conflicting paths (%s instead of %s for %d ants)", turnsText(bestTurns), turnsText(nonOverlapTurns), ants)
+}
+
+// turnsText writes a count of predictTurns, which may be unreachableTurns
+func turnsText(turns int) string {
+	if turns == unreachableTurns {
+		return "more paths than the set has"
+	}
+	return fmt.Sprintf("%d turns", turns)
+}
+
+// ----- Load strategies from a Go plugin -----
+// Plugins cannot see this package's types, so they export plain symbols:
+//
+//	var Name = "my-strategy"
+//	func FindPaths(start, end string, links map[string][]string) [][]string
+//
+// The plugin gets its own copy of the tunnels. Each path that does not go
+// from start to end through existing tunnels, or that shares a room with
+// an earlier path, is reported and dropped; the others are kept.
+func loadPlugin(path string) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return err
+	}
+	nameSym, err := p.Lookup("Name")
+	if err != nil {
+		return err
+	}
+	findSym, err := p.Lookup("FindPaths")
+	if err != nil {
+		return err
+	}
+	name, ok := nameSym.(*string)
+	if !ok {
+		return fmt.Errorf("plugin %s: Name must be a string variable", path)
+	}
+	find, ok := findSym.(func(string, string, map[string][]string) [][]string)
+	if !ok {
+		return fmt.Errorf("plugin %s: FindPaths has the wrong signature", path)
+	}
+	if _, exists := strategies[*name]; exists {
+		return fmt.Errorf("plugin %s: strategy %q already exists", path, *name)
+	}
+	RegisterStrategy(*name, func() Strategy {
+		return func(f *Farm) [][]string {
+			links := make(map[string][]string, len(f.Rooms))
+			for name, room := range f.Rooms {
+				links[name] = append([]string(nil), room.Links...)
+			}
+			var kept [][]string
+			for i, p := range find(f.Start, f.End, links) {
+				if err := validatePaths(f, append(kept, p)); err != nil {
+					writeError(os.Stderr, fmt.Errorf("plugin %s: dropping path %d: %w", *name, i+1, err))
+					continue
+				}
+				kept = append(kept, p)
+			}
+			return kept
+		}
+	})
+	loadedPlugins = append(loadedPlugins, path+": "+*name)
+	return nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"syscall"
+)
+
+// Time-expanded graphs with more nodes than this are refused
+const defaultMaxTimeNodes = 1000000
+
+// ----- Exact scheduling over the time-expanded graph -----
+// The farm is copied once per turn: node (room, t) is the room at the end
+// of turn t. An ant either waits, (r, t) -> (r, t+1), or goes through a
+// tunnel, (u, t) -> (v, t+1). Every intermediate room is split in two with
+// a capacity of one ant in between, and every tunnel carries one ant per
+// turn, so a flow of value Ants that reaches the end by turn T is a legal
+// schedule of T turns, ants waiting on the way included. The smallest such
+// T is the true optimum, without assuming that ants follow fixed paths.
+type timeGraph struct {
+	network
+	farm  *Farm
+	turns int
+	sink  int
+}
+
+// in and out are the two halves of room id at the end of turn t
+func (g *timeGraph) in(id, t int) int  { return 2 * (t*len(g.farm.Names) + id) }
+func (g *timeGraph) out(id, t int) int { return g.in(id, t) + 1 }
+
+func newTimeGraph(f *Farm, turns int) *timeGraph {
+	nodes := 2*len(f.Names)*(turns+1) + 1
+	g := &timeGraph{network: newNetwork(nodes), farm: f, turns: turns, sink: nodes - 1}
+	start, end := f.Rooms[f.Start].ID, f.Rooms[f.End].ID
+	for t := 0; t <= turns; t++ {
+		for id, name := range f.Names {
+			switch id {
+			case start:
+				g.addArc(g.in(id, t), g.out(id, t), f.Ants, 0)
+			case end:
+				// Ants stay in the end room once they get there
+				g.addArc(g.in(id, t), g.sink, f.Ants, 0)
+				continue
+			default:
+				g.addArc(g.in(id, t), g.out(id, t), 1, 0)
+			}
+			if t == turns {
+				continue
+			}
+			g.addArc(g.out(id, t), g.in(id, t+1), f.Ants, 0)
+			seen := make(map[string]bool) // repeated tunnels are one tunnel
+			for _, next := range f.Rooms[name].Links {
+				if seen[next] || next == f.Start {
+					continue
+				}
+				seen[next] = true
+				g.addArc(g.out(id, t), g.in(f.Rooms[next].ID, t+1), 1, 1)
+			}
+		}
+	}
+	return g
+}
+
+// flow sends up to want ants from the start room at turn 0 to the sink,
+// each time along the cheapest residual path, so the moves are as few as
+// possible. It returns how many ants got through.
+func (g *timeGraph) flow(want int) int {
+	source := g.out(g.farm.Rooms[g.farm.Start].ID, 0)
+	sent := 0
+	dist := make([]int, len(g.arcsFrom))
+	via := make([]int, len(g.arcsFrom))
+	queued := make([]bool, len(g.arcsFrom))
+	for sent < want {
+		for i := range dist {
+			dist[i], via[i] = -1, -1
+		}
+		dist[source] = 0
+		queue := []int{source}
+		queued[source] = true
+		for len(queue) > 0 {
+			node := queue[0]
+			queue = queue[1:]
+			queued[node] = false
+			for _, a := range g.arcsFrom[node] {
+				arc := g.arcs[a]
+				if arc.cap == 0 {
+					continue
+				}
+				if d := dist[node] + arc.cost; dist[arc.to] < 0 || d < dist[arc.to] {
+					dist[arc.to], via[arc.to] = d, a
+					if !queued[arc.to] {
+						queued[arc.to] = true
+						queue = append(queue, arc.to)
+					}
+				}
+			}
+		}
+		// Distances never drop below zero: every path found is at least
+		// as long as the previous one, so -1 can mark unreached nodes
+		if dist[g.sink] < 0 {
+			break
+		}
+		push := want - sent
+		for node := g.sink; node != source; node = g.arcs[via[node]^1].to {
+			if c := g.arcs[via[node]].cap; c < push {
+				push = c
+			}
+		}
+		for node := g.sink; node != source; node = g.arcs[via[node]^1].to {
+			g.arcs[via[node]].cap -= push
+			g.arcs[via[node]^1].cap += push
+		}
+		sent += push
+	}
+	return sent
+}
+
+// schedule follows the flow of every ant and numbers the ants in the order
+// they leave the start room.
+func (g *timeGraph) schedule() []Turn {
+	f := g.farm
+	var routes [][]string // room of one ant at the end of each turn
+	for {
+		node := g.out(f.Rooms[f.Start].ID, 0)
+		route := []string{f.Start}
+		for node != g.sink {
+			next := -1
+			for _, a := range g.arcsFrom[node] {
+				// Forward arcs are the even ones; their flow is the
+				// capacity gained by the reverse arc
+				if a%2 == 0 && g.arcs[a^1].cap > 0 {
+					next = a
+					break
+				}
+			}
+			if next < 0 {
+				break
+			}
+			g.arcs[next^1].cap--
+			node = g.arcs[next].to
+			if node%2 == 0 && node != g.sink {
+				route = append(route, f.Names[node/2%len(f.Names)])
+			}
+		}
+		if node != g.sink {
+			break
+		}
+		routes = append(routes, route)
+	}
+
+	departure := func(route []string) int {
+		for t, room := range route {
+			if room != f.Start {
+				return t
+			}
+		}
+		return len(route)
+	}
+	sort.SliceStable(routes, func(i, j int) bool { return departure(routes[i]) < departure(routes[j]) })
+
+	turns := make([]Turn, g.turns)
+	last := 0
+	for i, route := range routes {
+		for t := 1; t < len(route); t++ {
+			if route[t] != route[t-1] {
+				turns[t-1] = append(turns[t-1], Move{Ant: i + 1, Room: route[t]})
+				if t > last {
+					last = t
+				}
+			}
+		}
+	}
+	return turns[:last]
+}
+
+// optimalSchedule searches for the fewest turns between the shortest path
+// and the turns of the regular solver, which are always enough.
+func optimalSchedule(f *Farm, maxNodes int) ([]Turn, error) {
+	paths := autoPaths(f)
+	if len(paths) == 0 {
+		return nil, errNoPath
+	}
+	if f.Ants == 0 {
+		return nil, nil
+	}
+	low, high := len(paths[0])-1, len(simulateAnts(paths, distributeAnts(f.Ants, paths, 0)))
+	for _, p := range paths {
+		if len(p)-1 < low {
+			low = len(p) - 1
+		}
+	}
+	if nodes := 2 * len(f.Names) * (high + 1); nodes > maxNodes {
+		return nil, fmt.Errorf("the time-expanded graph would have %d nodes, more than %d", nodes, maxNodes)
+	}
+	for low < high {
+		mid := low + (high-low)/2
+		if newTimeGraph(f, mid).flow(f.Ants) == f.Ants {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+	g := newTimeGraph(f, high)
+	if g.flow(f.Ants) != f.Ants {
+		return nil, errors.New("internal error: the solver's turns do not fit the time-expanded graph")
+	}
+	return g.schedule(), nil
+}
+
+// ----- optimum: print a schedule with the fewest possible turns -----
+// lem-in optimum map.txt [--max-nodes N]
+// Meant for studying small and medium maps: the time-expanded graph grows
+// with rooms times turns.
+func runOptimum(args []string) {
+	fs := flag.NewFlagSet("optimum", flag.ExitOnError)
+	maxNodes := fs.Int("max-nodes", defaultMaxTimeNodes, "refuse maps whose time-expanded graph has more nodes than this")
+	args, _ = parseFlags(fs, args)
+	if len(args) != 1 {
+		fmt.Println("Usage: go run . optimum [--max-nodes N] input.txt")
+		return
+	}
+	farm, err := parseInput(args[0], ParseOptions{})
+	if err != nil {
+		reportError(err)
+		return
+	}
+	turns, err := optimalSchedule(farm, *maxNodes)
+	if err != nil {
+		reportError(err)
+		return
+	}
+	if err := checkSolution(Solution{Farm: farm, Turns: turns}); err != nil {
+		fmt.Fprintln(os.Stderr, "Internal error: optimal schedule is invalid:", err)
+		os.Exit(exitInternal)
+	}
+
+	paths := autoPaths(farm)
+	solver := len(simulateAnts(paths, distributeAnts(farm.Ants, paths, 0)))
+	fmt.Fprintf(os.Stderr, "Optimum: %d turns (solver: %d)\n", len(turns), solver)
+	prog := newProgress()
+	err = streamAnts(os.Stdout, writeTurn, replayTurns(turns), prog, nil)
+	if err != nil && !errors.Is(err, syscall.EPIPE) {
+		writeError(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import "sort"
+
+// ----- Canonical list of tunnels -----
+// The Links of the rooms list every tunnel twice, once from each end, and
+// a tunnel written twice in the map twice more. Tunnels gives each tunnel
+// once, with its two rooms in alphabetical order, sorted: the form to
+// compare, hash or check farms by. Exports that rewrite a map keep the
+// input order of tunnelList instead.
+
+// Tunnel joins rooms A and B, with A < B
+type Tunnel struct {
+	A, B string
+}
+
+func newTunnel(a, b string) Tunnel {
+	if b < a {
+		a, b = b, a
+	}
+	return Tunnel{a, b}
+}
+
+// Tunnels returns the distinct tunnels of the farm, by A then B. The
+// parsers build the list before returning the farm; a farm edited through
+// addTunnel has it sorted again on the next call. The result is shared:
+// do not modify it.
+func (f *Farm) Tunnels() []Tunnel {
+	f.tunnelsMu.Lock()
+	defer f.tunnelsMu.Unlock()
+	if !f.tunnelsSorted {
+		sort.Slice(f.tunnels, func(i, j int) bool {
+			a, b := f.tunnels[i], f.tunnels[j]
+			return a.A < b.A || (a.A == b.A && a.B < b.B)
+		})
+		distinct := f.tunnels[:0]
+		for i, t := range f.tunnels {
+			if i == 0 || t != f.tunnels[i-1] {
+				distinct = append(distinct, t)
+			}
+		}
+		f.tunnels, f.tunnelsSorted = distinct, true
+	}
+	return f.tunnels
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ----- vectors: export test vectors for other implementations -----
+// lem-in vectors [--out dir] [--max-rooms N] map1.txt ...
+// For every map the exact solver can handle, dir gets:
+//
+//	name.map       the map in canonical form
+//	name.turns     the minimum number of turns, alone on a line
+//	name.solution  a schedule reaching that minimum, one line per turn
+//
+// and index.json lists them all. Any valid schedule with the same number
+// of turns is as good as the reference one.
+type testVector struct {
+	Name     string `json:"name"`
+	Map      string `json:"map"`
+	Solution string `json:"solution"`
+	Ants     int    `json:"ants"`
+	Rooms    int    `json:"rooms"`
+	MinTurns int    `json:"minTurns"`
+}
+
+func runVectors(args []string) {
+	fs := flag.NewFlagSet("vectors", flag.ExitOnError)
+	out := fs.String("out", "vectors", "directory to write the vectors to")
+	maxRooms := fs.Int("max-rooms", defaultExactRooms, "skip maps with more rooms than this")
+	args, _ = parseFlags(fs, args)
+	if len(args) < 1 {
+		fmt.Println("Usage: go run . vectors [--out dir] [--max-rooms N] map1.txt ...")
+		return
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		reportError(err)
+		return
+	}
+
+	var index []testVector
+	names := make(map[string]int)
+	failed := false
+	for _, filename := range args {
+		name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+		if names[name]++; names[name] > 1 {
+			name += "-" + strconv.Itoa(names[name])
+		}
+		v, err := writeVector(*out, name, filename, *maxRooms)
+		if err != nil {
+			reportErrorf("%s: %w", filename, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: %d turns\n", name, v.MinTurns)
+		index = append(index, v)
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(*out, "index.json"), append(data, '\n'), 0o644)
+	}
+	if err != nil {
+		reportError(err)
+		failed = true
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func writeVector(dir, name, filename string, maxRooms int) (testVector, error) {
+	farm, err := parseInput(filename, ParseOptions{})
+	if err != nil {
+		return testVector{}, err
+	}
+	minTurns, paths, err := exactMinTurns(farm, maxRooms)
+	if err != nil {
+		return testVector{}, err
+	}
+	dist := distributeAnts(farm.Ants, paths, 0)
+	sol := Solution{Farm: farm, Paths: paths, Distribution: dist, Turns: simulateAnts(paths, dist)}
+	if err := checkSolution(sol); err != nil {
+		return testVector{}, fmt.Errorf("reference solution is invalid: %v", err)
+	}
+	if len(sol.Turns) != minTurns {
+		return testVector{}, fmt.Errorf("reference solution takes %d turns instead of %d", len(sol.Turns), minTurns)
+	}
+
+	v := testVector{
+		Name:     name,
+		Map:      name + ".map",
+		Solution: name + ".solution",
+		Ants:     farm.Ants,
+		Rooms:    len(farm.Rooms),
+		MinTurns: minTurns,
+	}
+	files := map[string]string{
+		v.Map:           farm.String(),
+		name + ".turns": strconv.Itoa(minTurns) + "\n",
+		v.Solution:      sol.String(),
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+			return testVector{}, err
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// ----- What is running (--version [--json]) -----
+// The version and commit come from the build information the go command
+// embeds: a tagged module version for go install, "(devel)" and the VCS
+// revision for a build from a checkout. The lists name everything that
+// can be selected on the command line, including the strategies of the
+// plugins given with --plugin, so that a bug report says exactly what ran.
+type versionInfo struct {
+	Version     string   `json:"version"`
+	Commit      string   `json:"commit,omitempty"`
+	CommitTime  string   `json:"commit_time,omitempty"`
+	Modified    bool     `json:"modified,omitempty"` // built with uncommitted changes
+	GoVersion   string   `json:"go"`
+	Platform    string   `json:"platform"`
+	BuildTags   []string `json:"build_tags"`
+	Strategies  []string `json:"strategies"`
+	Encoders    []string `json:"encoders"`
+	Heuristics  []string `json:"heuristics"`
+	Objectives  []string `json:"objectives"`
+	Assignments []string `json:"assignments"`
+	Plugins     []string `json:"plugins"`
+}
+
+// Plugins loaded with --plugin, as "file: strategy"
+var loadedPlugins []string
+
+func buildVersion() versionInfo {
+	v := versionInfo{
+		Version:    "unknown",
+		GoVersion:  runtime.Version(),
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+		BuildTags:  []string{},
+		Strategies: strategyNames(),
+		Encoders:   encoderNames(),
+		Heuristics: heuristicNames(),
+		Plugins:    append([]string{}, loadedPlugins...),
+	}
+	for name := range objectives {
+		v.Objectives = append(v.Objectives, name)
+	}
+	sort.Strings(v.Objectives)
+	v.Assignments = []string{"balanced"}
+	for name := range assignments {
+		v.Assignments = append(v.Assignments, name)
+	}
+	sort.Strings(v.Assignments[1:])
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+	v.Version = info.Main.Version
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			v.Commit = s.Value
+		case "vcs.time":
+			v.CommitTime = s.Value
+		case "vcs.modified":
+			v.Modified = s.Value == "true"
+		case "-tags":
+			v.BuildTags = strings.Split(s.Value, ",")
+		}
+	}
+	return v
+}
+
+func writeVersion(w io.Writer, asJSON bool) error {
+	v := buildVersion()
+	if asJSON {
+		return json.NewEncoder(w).Encode(v)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "lem-in %s\n", v.Version)
+	if v.Commit != "" {
+		modified := ""
+		if v.Modified {
+			modified = ", modified"
+		}
+		fmt.Fprintf(&sb, "%-13s%s (%s%s)\n", "Commit:", v.Commit, v.CommitTime, modified)
+	}
+	fmt.Fprintf(&sb, "%-13s%s %s\n", "Go:", v.GoVersion, v.Platform)
+	list := func(title string, names []string) {
+		if len(names) == 0 {
+			names = []string{"none"}
+		}
+		fmt.Fprintf(&sb, "%-13s%s\n", title, strings.Join(names, ", "))
+	}
+	list("Build tags:", v.BuildTags)
+	list("Strategies:", v.Strategies)
+	list("Formats:", v.Encoders)
+	list("Heuristics:", v.Heuristics)
+	list("Objectives:", v.Objectives)
+	list("Assignments:", v.Assignments)
+	list("Plugins:", v.Plugins)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}